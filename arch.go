@@ -0,0 +1,32 @@
+package exec
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// archPath is the macOS tool used to run a binary under a particular architecture.
+const archPath = "/usr/bin/arch"
+
+// Architecture runs the command under the given architecture, "x86_64", "arm64" or "arm64e", on macOS. This allows
+// x86-only tools to be run under Rosetta on Apple Silicon, or the x86 slice of a universal binary to be selected.
+//
+// The command is run via arch(1), so it must be called after Path and Args are final, and the child's argv[0] becomes
+// its path.
+func Architecture(cmd *Cmd, arch string) error {
+	if runtime.GOOS != "darwin" {
+		return fmt.Errorf("exec: architecture selection is not supported on %s", runtime.GOOS)
+	}
+	switch arch {
+	case "x86_64", "arm64", "arm64e":
+	default:
+		return fmt.Errorf("exec: unknown architecture %q", arch)
+	}
+	args := []string{archPath, "-" + arch, cmd.Path}
+	if len(cmd.Args) > 1 {
+		args = append(args, cmd.Args[1:]...)
+	}
+	cmd.Path = archPath
+	cmd.Args = args
+	return nil
+}
@@ -0,0 +1,31 @@
+//go:build (linux || darwin) && (amd64 || arm64)
+
+package exec_test
+
+import (
+	"runtime"
+	"slices"
+	"testing"
+
+	"github.com/alecthomas/exec"
+)
+
+func TestArchitecture(t *testing.T) {
+	cmd := exec.Command("echo", "arch")
+	err := exec.Architecture(cmd, "x86_64")
+	if runtime.GOOS != "darwin" {
+		if err == nil {
+			t.Error("Expected architecture selection to fail outside macOS")
+		}
+		return
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"/usr/bin/arch", "-x86_64", "/bin/echo", "arch"}; !slices.Equal(cmd.Args, expected) {
+		t.Errorf("Expected %v, got %v", expected, cmd.Args)
+	}
+	if err := exec.Architecture(exec.Command("true"), "ppc"); err == nil {
+		t.Error("Expected an unknown architecture to be rejected")
+	}
+}
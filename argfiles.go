@@ -0,0 +1,39 @@
+package exec
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ArgsFromGlob expands a shell-style glob pattern into a sorted list of arguments.
+//
+// Unlike a shell without nullglob, a pattern that matches nothing expands to no arguments rather than to itself.
+func ArgsFromGlob(pattern string) ([]string, error) {
+	return filepath.Glob(pattern)
+}
+
+// ArgsFromFile reads arguments from a file, one per line.
+//
+// Blank lines and lines whose first non-blank character is '#' are skipped. Other lines are used verbatim, apart from
+// a trailing carriage return, so arguments may contain leading or trailing spaces.
+func ArgsFromFile(path string) ([]string, error) {
+	r, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close() //nolint
+	var args []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		args = append(args, line)
+	}
+	return args, scanner.Err()
+}
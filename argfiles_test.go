@@ -0,0 +1,48 @@
+package exec_test
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/alecthomas/exec"
+)
+
+func TestArgsFromGlob(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"b.go", "a.go", "c.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	args, err := exec.ArgsFromGlob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{filepath.Join(dir, "a.go"), filepath.Join(dir, "b.go")}
+	if !slices.Equal(args, expected) {
+		t.Errorf("Expected %v, got %v", expected, args)
+	}
+
+	args, err = exec.ArgsFromGlob(filepath.Join(dir, "*.none"))
+	if err != nil || len(args) != 0 {
+		t.Errorf("Expected no arguments for an unmatched pattern, got %v, %v", args, err)
+	}
+}
+
+func TestArgsFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "args")
+	content := "# comment\n--flag\n\n  # indented comment\nwith space \r\nlast"
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	args, err := exec.ArgsFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"--flag", "with space ", "last"}
+	if !slices.Equal(args, expected) {
+		t.Errorf("Expected %q, got %q", expected, args)
+	}
+}
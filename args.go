@@ -0,0 +1,97 @@
+//go:build (linux || darwin) && (amd64 || arm64)
+
+package exec
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"unsafe"
+)
+
+// ArgLimitError is returned when a command's arguments and environment exceed an operating system limit.
+type ArgLimitError struct {
+	// Index of the offending argument if the limit is per-argument, otherwise -1.
+	Index int
+	// Size in bytes of the argument, or of the combined arguments and environment.
+	Size int
+	// Limit that was exceeded.
+	Limit int
+}
+
+func (e *ArgLimitError) Error() string {
+	if e.Index >= 0 {
+		return fmt.Sprintf("argument %d is %d bytes, exceeding the per-argument limit of %d bytes", e.Index, e.Size, e.Limit)
+	}
+	return fmt.Sprintf("arguments and environment are %d bytes, exceeding the limit of %d bytes", e.Size, e.Limit)
+}
+
+// InvalidArgError is returned when an argument or environment variable cannot be passed to a process.
+type InvalidArgError struct {
+	// Env is true if the invalid value is an environment variable rather than an argument.
+	Env    bool
+	Index  int
+	Reason string
+}
+
+func (e *InvalidArgError) Error() string {
+	kind := "argument"
+	if e.Env {
+		kind = "environment variable"
+	}
+	return fmt.Sprintf("%s %d %s", kind, e.Index, e.Reason)
+}
+
+// ArgMax returns the maximum combined size in bytes of arguments and environment for a new process, as counted by
+// ArgSize.
+func ArgMax() int {
+	return argMax()
+}
+
+// ArgSize returns the number of bytes cmd's arguments and environment will consume in the child, including the
+// additional arguments used to launch the intermediary.
+func ArgSize(cmd *Cmd) int {
+	env := cmd.Env
+	if env == nil {
+		env = os.Environ()
+	}
+	pointer := int(unsafe.Sizeof(uintptr(0)))
+	// Each string is NUL terminated, and argv and envp are NULL terminated arrays of pointers.
+	size := 2 * pointer
+	for _, s := range cmd.Unwrap().Args {
+		size += len(s) + 1 + pointer
+	}
+	for _, s := range env {
+		size += len(s) + 1 + pointer
+	}
+	return size
+}
+
+// CheckArgs returns an *ArgLimitError or *InvalidArgError if cmd's arguments or environment cannot be passed to the
+// child, rather than the command failing at Start with E2BIG or EINVAL.
+func CheckArgs(cmd *Cmd) error {
+	for i, arg := range cmd.Args {
+		if strings.IndexByte(arg, 0) >= 0 {
+			return &InvalidArgError{Index: i, Reason: "contains a NUL byte"}
+		}
+		if limit := maxArgStrlen(); limit > 0 && len(arg)+1 > limit {
+			return &ArgLimitError{Index: i, Size: len(arg) + 1, Limit: limit}
+		}
+	}
+	env := cmd.Env
+	if env == nil {
+		env = os.Environ()
+	}
+	for i, kv := range env {
+		if strings.IndexByte(kv, 0) >= 0 {
+			return &InvalidArgError{Env: true, Index: i, Reason: "contains a NUL byte"}
+		}
+		if limit := maxArgStrlen(); limit > 0 && len(kv)+1 > limit {
+			return &InvalidArgError{Env: true, Index: i, Reason: fmt.Sprintf("exceeds the per-string limit of %d bytes", limit)}
+		}
+	}
+	if size, limit := ArgSize(cmd), ArgMax(); size > limit {
+		return &ArgLimitError{Index: -1, Size: size, Limit: limit}
+	}
+	return nil
+}
@@ -0,0 +1,18 @@
+//go:build amd64 || arm64
+
+package exec
+
+import "syscall"
+
+func argMax() int {
+	value, err := syscall.SysctlUint32("kern.argmax")
+	if err != nil {
+		return 256 * 1024
+	}
+	return int(value)
+}
+
+// maxArgStrlen is the limit on a single argument, which macOS does not have.
+func maxArgStrlen() int {
+	return 0
+}
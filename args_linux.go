@@ -0,0 +1,24 @@
+//go:build amd64 || arm64
+
+package exec
+
+import "syscall"
+
+// argMax mirrors the kernel's limit: a quarter of the stack size limit, but no less than the historical 128KiB and no
+// more than three quarters of the default 8MiB stack.
+func argMax() int {
+	const (
+		minArgMax = 128 * 1024
+		maxArgMax = 8 * 1024 * 1024 / 4 * 3
+	)
+	var limit syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_STACK, &limit); err != nil || limit.Cur/4 > maxArgMax {
+		return maxArgMax
+	}
+	return max(int(limit.Cur/4), minArgMax)
+}
+
+// maxArgStrlen is the limit on a single argument or environment string (MAX_ARG_STRLEN).
+func maxArgStrlen() int {
+	return 32 * 4096
+}
@@ -0,0 +1,58 @@
+//go:build (linux || darwin) && (amd64 || arm64)
+
+package exec_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/exec"
+)
+
+func FuzzArgumentPassing(f *testing.F) {
+	f.Add("", "simple")
+	f.Add("with space", "")
+	f.Add("line\nbreak", "tab\there")
+	f.Add("-", "--")
+	f.Add("$HOME", "'quoted'")
+	f.Add("日本語", "\x01\x7f\xff")
+	f.Fuzz(func(t *testing.T, a, b string) {
+		cmd := exec.Command("sh", "-c", `printf '%s\0' "$@"`, "sh", a, b)
+		if err := exec.CheckArgs(cmd); err != nil {
+			var invalid *exec.InvalidArgError
+			if !errors.As(err, &invalid) {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			return
+		}
+		output, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("Command failed: %v", err)
+		}
+		expected := a + "\x00" + b + "\x00"
+		if string(output) != expected {
+			t.Errorf("Expected %q, got %q", expected, string(output))
+		}
+	})
+}
+
+func TestCheckArgs(t *testing.T) {
+	if err := exec.CheckArgs(exec.Command("echo", "ok")); err != nil {
+		t.Errorf("Expected valid arguments, got %v", err)
+	}
+
+	var invalid *exec.InvalidArgError
+	if err := exec.CheckArgs(exec.Command("echo", "nul\x00byte")); !errors.As(err, &invalid) || invalid.Index != 1 {
+		t.Errorf("Expected InvalidArgError for argument 1, got %v", err)
+	}
+
+	args := make([]string, exec.ArgMax()/1000+1)
+	for i := range args {
+		args[i] = strings.Repeat("x", 1000)
+	}
+	var limit *exec.ArgLimitError
+	if err := exec.CheckArgs(exec.Command("echo", args...)); !errors.As(err, &limit) || limit.Index != -1 {
+		t.Errorf("Expected combined ArgLimitError, got %v", err)
+	}
+}
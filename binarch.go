@@ -0,0 +1,123 @@
+package exec
+
+import (
+	"debug/elf"
+	"debug/macho"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// ArchitectureError is returned by CheckArchitecture when a binary cannot run natively on the host.
+type ArchitectureError struct {
+	Path string
+	// Binary is the architecture, or architectures of a universal binary, the binary was built for, as GOARCH values
+	// where possible.
+	Binary []string
+	// Host is the architecture of the host, as a GOARCH value.
+	Host string
+	// Emulator that would run the binary, as reported by Emulator, if the error was returned by RequireNative.
+	Emulator string
+}
+
+func (e *ArchitectureError) Error() string {
+	msg := fmt.Sprintf("%s: binary is %s, host is %s", e.Path, strings.Join(e.Binary, "/"), e.Host)
+	if e.Emulator != "" {
+		msg += " (emulated by " + e.Emulator + ")"
+	}
+	return msg
+}
+
+// CheckArchitecture returns an *ArchitectureError if the command's binary is an ELF or Mach-O executable built for an
+// architecture the host cannot run natively, rather than the command failing at Start with ENOEXEC or "exec format
+// error". 32-bit x86 binaries are accepted on amd64, as are binaries that will be run by an emulator, such as x86-64
+// binaries on Apple Silicon or binaries registered with qemu-user on Linux. Use RequireNative to reject emulation.
+//
+// Scripts, and binaries in other formats or that cannot be read, are not checked.
+func CheckArchitecture(cmd *Cmd) error {
+	if !filepath.IsAbs(cmd.Path) {
+		return nil
+	}
+	archs := binaryArchitectures(cmd.Path)
+	if len(archs) == 0 {
+		return nil
+	}
+	if slices.ContainsFunc(archs, nativeArch) || emulator(cmd.Path, archs) != "" {
+		return nil
+	}
+	return &ArchitectureError{Path: cmd.Path, Binary: archs, Host: runtime.GOARCH}
+}
+
+// nativeArch reports whether the host can run a binary for arch natively.
+func nativeArch(arch string) bool {
+	return arch == runtime.GOARCH || (arch == "386" && runtime.GOARCH == "amd64")
+}
+
+// binaryArchitectures returns the architectures the executable at path was built for, or nil if it is not an ELF or
+// Mach-O file.
+func binaryArchitectures(path string) []string {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close() //nolint
+	if e, err := elf.NewFile(f); err == nil {
+		return []string{elfArchitecture(e)}
+	}
+	if fat, err := macho.NewFatFile(f); err == nil {
+		var archs []string
+		for _, arch := range fat.Arches {
+			archs = append(archs, machoArchitecture(arch.Cpu))
+		}
+		return archs
+	}
+	if m, err := macho.NewFile(f); err == nil {
+		return []string{machoArchitecture(m.Cpu)}
+	}
+	return nil
+}
+
+func elfArchitecture(e *elf.File) string {
+	switch e.Machine {
+	case elf.EM_X86_64:
+		return "amd64"
+	case elf.EM_386:
+		return "386"
+	case elf.EM_AARCH64:
+		return "arm64"
+	case elf.EM_ARM:
+		return "arm"
+	case elf.EM_RISCV:
+		if e.Class == elf.ELFCLASS64 {
+			return "riscv64"
+		}
+	case elf.EM_PPC64:
+		if e.ByteOrder == binary.LittleEndian {
+			return "ppc64le"
+		}
+		return "ppc64"
+	case elf.EM_S390:
+		return "s390x"
+	case elf.EM_LOONGARCH:
+		return "loong64"
+	}
+	return strings.ToLower(strings.TrimPrefix(e.Machine.String(), "EM_"))
+}
+
+func machoArchitecture(cpu macho.Cpu) string {
+	switch cpu {
+	case macho.CpuAmd64:
+		return "amd64"
+	case macho.Cpu386:
+		return "386"
+	case macho.CpuArm64:
+		return "arm64"
+	case macho.CpuArm:
+		return "arm"
+	}
+	return cpu.String()
+}
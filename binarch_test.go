@@ -0,0 +1,63 @@
+//go:build (linux || darwin) && (amd64 || arm64)
+
+package exec_test
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/alecthomas/exec"
+)
+
+func TestCheckArchitecture(t *testing.T) {
+	if err := exec.CheckArchitecture(exec.Command("sh")); err != nil {
+		t.Errorf("Expected a native binary to pass, got %v", err)
+	}
+
+	// A header-only ELF executable for the other supported architecture.
+	machine, other := elf.EM_AARCH64, "arm64"
+	if runtime.GOARCH == "arm64" {
+		machine, other = elf.EM_X86_64, "amd64"
+	}
+	header := elf.Header64{Type: uint16(elf.ET_EXEC), Machine: uint16(machine), Version: uint32(elf.EV_CURRENT), Ehsize: 64}
+	copy(header.Ident[:], elf.ELFMAG)
+	header.Ident[elf.EI_CLASS] = byte(elf.ELFCLASS64)
+	header.Ident[elf.EI_DATA] = byte(elf.ELFDATA2LSB)
+	header.Ident[elf.EI_VERSION] = byte(elf.EV_CURRENT)
+	var buf bytes.Buffer
+	if err := binary.Write(&buf, binary.LittleEndian, header); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "foreign")
+	if err := os.WriteFile(path, buf.Bytes(), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	err := exec.CheckArchitecture(exec.Command(path))
+	var archErr *exec.ArchitectureError
+	if !errors.As(err, &archErr) {
+		t.Fatalf("Expected an ArchitectureError, got %v", err)
+	}
+	if len(archErr.Binary) != 1 || archErr.Binary[0] != other || archErr.Host != runtime.GOARCH {
+		t.Errorf("Unexpected error %v", err)
+	}
+	if !errors.As(exec.Validate(exec.Command(path)), &archErr) {
+		t.Error("Expected Validate to report the architecture mismatch")
+	}
+
+	if !errors.As(exec.RequireNative(exec.Command(path)), &archErr) {
+		t.Error("Expected RequireNative to reject a foreign binary")
+	}
+	if err := exec.RequireNative(exec.Command("sh")); err != nil {
+		t.Errorf("Expected a native binary to pass, got %v", err)
+	}
+	if emulator := exec.Emulator(exec.Command("sh")); emulator != "" {
+		t.Errorf("Expected a native binary to have no emulator, got %q", emulator)
+	}
+}
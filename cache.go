@@ -0,0 +1,102 @@
+//go:build (linux || darwin) && (amd64 || arm64)
+
+package exec
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// cachePrefix is the file name prefix of cached intermediaries, which are named <prefix><target>-<sha256>, where the
+// hash is of the embedded, compressed intermediary.
+const cachePrefix = "intermediary-"
+
+// cacheDir returns the per-user directory intermediaries are cached in.
+func cacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "go-exec"), nil
+}
+
+// extractCached returns the path of the cached intermediary for target, extracting it into the cache if it is not
+// already there, and reports whether it was extracted.
+//
+// The cache is keyed by the hash of the intermediary, so processes built with different versions of this package
+// share it safely, and a cached copy is only used if it is owned by the current user, not writable by others, and of
+// the expected size and checksum.
+func extractCached(fsys fs.FS, target string) (path string, fresh bool, err error) {
+	dir, err := cacheDir()
+	if err != nil {
+		return "", false, err
+	}
+	compressed, err := fs.ReadFile(fsys, "intermediary/intermediary-"+target+".gz")
+	if err != nil {
+		return "", false, err
+	}
+	sum := sha256.Sum256(compressed)
+	path = filepath.Join(dir, cachePrefix+target+"-"+hex.EncodeToString(sum[:]))
+
+	// Processes starting concurrently wait for whichever of them extracts the intermediary, rather than each extracting
+	// their own copy. The lock is released by the operating system if the holder dies.
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", false, err
+	}
+	lock, err := os.OpenFile(path+".lock", os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return "", false, err
+	}
+	defer lock.Close() //nolint
+	if err := syscall.Flock(int(lock.Fd()), syscall.LOCK_EX); err != nil {
+		return "", false, fmt.Errorf("lock %s: %w", lock.Name(), err)
+	}
+	defer syscall.Flock(int(lock.Fd()), syscall.LOCK_UN) //nolint
+
+	if info, err := os.Lstat(path); err == nil && info.Mode().IsRegular() && info.Mode().Perm()&0022 == 0 {
+		stat, ok := info.Sys().(*syscall.Stat_t)
+		var intact bool
+		if signsIntermediary() {
+			// Signing changes the file, so a signed copy is checked against its signature, having been checked against
+			// the embedded checksum before it was signed.
+			intact = verifySignature(path) == nil
+		} else {
+			intact = info.Size() == gzipSize(compressed) && verifyIntermediary(path, target) == nil
+		}
+		if ok && int(stat.Uid) == os.Getuid() && intact {
+			return path, false, nil
+		}
+		// A copy of the wrong size or checksum was truncated or modified, so replace it.
+		_ = os.Remove(path)
+	}
+	tmp, err := Extract(fsys, target, dir)
+	if err != nil {
+		return "", false, err
+	}
+	// Renaming is atomic, so concurrent processes never execute a partially written copy.
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return "", false, err
+	}
+	return path, true, nil
+}
+
+// gzipSize returns the uncompressed size recorded in the trailer of a gzip stream, modulo 2^32, or -1.
+func gzipSize(compressed []byte) int64 {
+	if len(compressed) < 4 {
+		return -1
+	}
+	return int64(binary.LittleEndian.Uint32(compressed[len(compressed)-4:]))
+}
+
+// isCached reports whether path is a cached intermediary, which is shared with other processes.
+func isCached(path string) bool {
+	return strings.HasPrefix(filepath.Base(path), cachePrefix)
+}
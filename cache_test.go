@@ -0,0 +1,45 @@
+//go:build (linux || darwin) && (amd64 || arm64)
+
+package exec_test
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/alecthomas/exec"
+)
+
+func TestExtractCached(t *testing.T) {
+	if runtime.GOOS == "linux" {
+		t.Skip("the intermediary is held in memory on Linux")
+	}
+	if err := exec.Command("true").Run(); err != nil {
+		t.Fatal(err)
+	}
+	path := exec.GetStats().IntermediaryPath
+	if cache, _ := os.UserCacheDir(); filepath.Dir(path) != filepath.Join(cache, "go-exec") {
+		t.Fatalf("Expected the intermediary to be cached, got %s", path)
+	}
+	if err := exec.Cleanup(); err != nil {
+		t.Fatal(err)
+	}
+	if err := exec.Command("true").Run(); err != nil {
+		t.Fatal(err)
+	}
+	if reused := exec.GetStats().IntermediaryPath; reused != path {
+		t.Errorf("Expected %s to be reused, got %s", path, reused)
+	}
+
+	// A corrupted copy is replaced.
+	if err := exec.Cleanup(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Truncate(path, 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := exec.Command("true").Run(); err != nil {
+		t.Errorf("Expected a truncated intermediary to be replaced: %v", err)
+	}
+}
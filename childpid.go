@@ -0,0 +1,48 @@
+//go:build (linux || darwin) && (amd64 || arm64)
+
+package exec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ChildPID returns the pid of the command itself, rather than of the intermediary in cmd.Process, eg. to look up its
+// /proc entries or attach a tracer to it. The command must have been started.
+//
+// The intermediary forks the command shortly after it starts, so ChildPID waits until it has done so, the
+// intermediary exits, or ctx is done. The command is found by listing the intermediary's process group, so it is the
+// child of the inner watchdog rather than any process the command has started itself. A command run directly by
+// StrategyPdeathsig is its own process.
+func ChildPID(ctx context.Context, cmd *Cmd) (int, error) {
+	if cmd.Process == nil {
+		return 0, errors.New("exec: not started")
+	}
+	pgid := cmd.Process.Pid
+	if cmd.direct {
+		return pgid, nil
+	}
+	ticker := time.NewTicker(5 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		parents, err := groupParents(pgid)
+		if err != nil {
+			return 0, err
+		}
+		if _, ok := parents[pgid]; !ok {
+			return 0, fmt.Errorf("intermediary %d exited before the command was found", pgid)
+		}
+		for pid, parent := range parents {
+			if parent != pgid && parents[parent] == pgid {
+				return pid, nil
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return 0, context.Cause(ctx)
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,38 @@
+//go:build (linux || darwin) && (amd64 || arm64)
+
+package exec_test
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/exec"
+)
+
+func TestChildPID(t *testing.T) {
+	cmd := exec.Command("sh", "-c", "echo $$; sleep 0.2")
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	pid, err := exec.ChildPID(ctx, cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if pid == cmd.Process.Pid {
+		t.Errorf("Expected the child pid to differ from the intermediary pid %d", pid)
+	}
+	if got := strings.TrimSpace(stdout.String()); got != strconv.Itoa(pid) {
+		t.Errorf("Expected %d, got %q", pid, got)
+	}
+}
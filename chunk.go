@@ -0,0 +1,97 @@
+//go:build (linux || darwin) && (amd64 || arm64)
+
+package exec
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"unsafe"
+)
+
+// chunkHeadroom is reserved from ArgMax when chunking, as xargs does, for the kernel's own bookkeeping.
+const chunkHeadroom = 2048
+
+// ChunkArgs splits items into as few commands as possible, each running base with a subset of items appended to its
+// arguments, such that no command exceeds ArgMax.
+//
+// Each command copies base's Dir, Env, Stderr and SysProcAttr, and is bound to ctx. base itself is never run. An
+// *ArgLimitError is returned if a single item cannot fit in a command.
+func ChunkArgs(ctx context.Context, base *Cmd, items []string) ([]*Cmd, error) {
+	pointer := int(unsafe.Sizeof(uintptr(0)))
+	budget := ArgMax() - ArgSize(base) - chunkHeadroom
+	var cmds []*Cmd
+	var chunk []string
+	used := 0
+	flush := func() {
+		args := append(append([]string(nil), base.Args[1:]...), chunk...)
+		cmd := CommandContext(ctx, base.Args[0], args...)
+		cmd.Dir = base.Dir
+		cmd.Env = base.Env
+		cmd.Stderr = base.Stderr
+		if base.SysProcAttr != nil {
+			attr := *base.SysProcAttr
+			cmd.SysProcAttr = &attr
+		}
+		cmds = append(cmds, cmd)
+		chunk, used = nil, 0
+	}
+	for i, item := range items {
+		size := len(item) + 1 + pointer
+		if limit := maxArgStrlen(); limit > 0 && len(item)+1 > limit {
+			return nil, &ArgLimitError{Index: i, Size: len(item) + 1, Limit: limit}
+		}
+		if size > budget {
+			return nil, &ArgLimitError{Index: i, Size: size, Limit: budget}
+		}
+		if used+size > budget {
+			flush()
+		}
+		chunk = append(chunk, item)
+		used += size
+	}
+	if len(chunk) > 0 || len(cmds) == 0 {
+		flush()
+	}
+	return cmds, nil
+}
+
+// RunChunks runs cmds with up to parallelism running at once, and returns their combined stdout in order.
+//
+// A parallelism less than 1 runs the commands sequentially. All commands are run even if some fail, and their errors
+// are joined. A Stderr that is not an *os.File is written to in order once every command has finished, rather than
+// concurrently, so that the output of different commands is not interleaved.
+func RunChunks(cmds []*Cmd, parallelism int) ([]byte, error) {
+	parallelism = max(parallelism, 1)
+	outputs := make([][]byte, len(cmds))
+	errs := make([]error, len(cmds))
+	stderrs := make([]*bytes.Buffer, len(cmds))
+	sem := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+	for i, cmd := range cmds {
+		if _, ok := cmd.Stderr.(*os.File); cmd.Stderr != nil && !ok {
+			stderrs[i] = &bytes.Buffer{}
+		}
+		sem <- struct{}{}
+		wg.Go(func() {
+			defer func() { <-sem }()
+			if stderrs[i] != nil {
+				stderr := cmd.Stderr
+				cmd.Stderr = stderrs[i]
+				defer func() { cmd.Stderr = stderr }()
+			}
+			outputs[i], errs[i] = cmd.Output()
+		})
+	}
+	wg.Wait()
+	for i, cmd := range cmds {
+		if stderrs[i] != nil {
+			if _, err := cmd.Stderr.Write(stderrs[i].Bytes()); err != nil {
+				errs[i] = errors.Join(errs[i], err)
+			}
+		}
+	}
+	return bytes.Join(outputs, nil), errors.Join(errs...)
+}
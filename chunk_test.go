@@ -0,0 +1,75 @@
+//go:build (linux || darwin) && (amd64 || arm64)
+
+package exec_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/exec"
+)
+
+func TestChunkArgs(t *testing.T) {
+	items := make([]string, exec.ArgMax()/500)
+	for i := range items {
+		items[i] = fmt.Sprintf("%0500d", i)
+	}
+	base := exec.Command("sh", "-c", `printf '%s\n' "$@"`, "sh")
+	cmds, err := exec.ChunkArgs(context.Background(), base, items)
+	if err != nil {
+		t.Fatalf("ChunkArgs failed: %v", err)
+	}
+	if len(cmds) < 2 {
+		t.Fatalf("Expected items to be split across multiple commands, got %d", len(cmds))
+	}
+	for _, cmd := range cmds {
+		if err := exec.CheckArgs(cmd); err != nil {
+			t.Errorf("Chunk exceeds limits: %v", err)
+		}
+	}
+
+	output, err := exec.RunChunks(cmds, 4)
+	if err != nil {
+		t.Fatalf("RunChunks failed: %v", err)
+	}
+	expected := strings.Join(items, "\n") + "\n"
+	if string(output) != expected {
+		t.Errorf("Output does not match items in order (got %d bytes, expected %d)", len(output), len(expected))
+	}
+}
+
+func TestChunkArgsEmpty(t *testing.T) {
+	cmds, err := exec.ChunkArgs(context.Background(), exec.Command("echo", "base"), nil)
+	if err != nil {
+		t.Fatalf("ChunkArgs failed: %v", err)
+	}
+	output, err := exec.RunChunks(cmds, 1)
+	if err != nil {
+		t.Fatalf("RunChunks failed: %v", err)
+	}
+	if string(output) != "base\n" {
+		t.Errorf("Expected base command to run once, got %q", string(output))
+	}
+}
+
+func TestRunChunksStderr(t *testing.T) {
+	var stderr strings.Builder
+	var cmds []*exec.Cmd
+	var expected strings.Builder
+	for n := range 4 {
+		cmd := exec.Command("sh", "-c", `for i in 1 2 3; do echo "$0-$i" >&2; sleep 0.01; done`, fmt.Sprint(n))
+		cmd.Stderr = &stderr
+		cmds = append(cmds, cmd)
+		for i := 1; i <= 3; i++ {
+			fmt.Fprintf(&expected, "%d-%d\n", n, i)
+		}
+	}
+	if _, err := exec.RunChunks(cmds, 4); err != nil {
+		t.Fatalf("RunChunks failed: %v", err)
+	}
+	if stderr.String() != expected.String() {
+		t.Errorf("Expected stderr in order %q, got %q", expected.String(), stderr.String())
+	}
+}
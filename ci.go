@@ -0,0 +1,122 @@
+package exec
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Problem is an error or warning reported by a tool, such as a compiler diagnostic.
+type Problem struct {
+	File     string
+	Line     int
+	Column   int
+	Severity string // "error" or "warning"
+	Message  string
+}
+
+// ProblemParser extracts a Problem from a line of output, if it reports one.
+type ProblemParser func(line string) (Problem, bool)
+
+var compilerProblemPattern = regexp.MustCompile(`^([^\s:][^:]*):(\d+):(?:(\d+):)?\s*(?:(fatal error|error|warning):\s*)?(.+)$`)
+
+// CompilerProblems parses diagnostics of the form "file:line[:column]: [error|warning:] message", as written by GCC,
+// Clang, the Go toolchain and many linters. Diagnostics without a severity are errors.
+func CompilerProblems(line string) (Problem, bool) {
+	match := compilerProblemPattern.FindStringSubmatch(line)
+	if match == nil {
+		return Problem{}, false
+	}
+	problem := Problem{File: match[1], Severity: "error", Message: match[5]}
+	problem.Line, _ = strconv.Atoi(match[2])
+	problem.Column, _ = strconv.Atoi(match[3])
+	if match[4] == "warning" {
+		problem.Severity = "warning"
+	}
+	return problem, true
+}
+
+// CIFormat is a CI system's syntax for reporting problems from build output.
+type CIFormat int
+
+const (
+	CINone CIFormat = iota
+	// CIGitHubActions uses GitHub Actions workflow commands, eg. "::error file=a.c,line=3::message".
+	CIGitHubActions
+	// CITeamCity uses TeamCity service messages, eg. "##teamcity[message text='...' status='ERROR']".
+	CITeamCity
+)
+
+// DetectCI returns the CIFormat of the CI system this process is running under, from its environment variables.
+func DetectCI() CIFormat {
+	switch {
+	case os.Getenv("GITHUB_ACTIONS") == "true":
+		return CIGitHubActions
+	case os.Getenv("TEAMCITY_VERSION") != "":
+		return CITeamCity
+	default:
+		return CINone
+	}
+}
+
+// AnnotateProblems writes a CI annotation after each line of the command's stdout and stderr that parsers recognise
+// as a problem, so that they are shown natively in the CI system's UI. Lines are otherwise unchanged. It must be
+// called after Stdout and Stderr are set and before Start, and does nothing if format is CINone.
+func AnnotateProblems(cmd *Cmd, format CIFormat, parsers ...ProblemParser) {
+	if format == CINone {
+		return
+	}
+	FilterOutput(cmd, func(line []byte) []byte {
+		text := strings.TrimRight(string(line), "\r\n")
+		for _, parse := range parsers {
+			if problem, ok := parse(text); ok {
+				out := append([]byte(nil), line...)
+				if !strings.HasSuffix(string(line), "\n") {
+					out = append(out, '\n')
+				}
+				return append(out, formatProblem(format, problem)+"\n"...)
+			}
+		}
+		return line
+	})
+}
+
+func formatProblem(format CIFormat, problem Problem) string {
+	switch format {
+	case CIGitHubActions:
+		props := "file=" + githubEscapeProperty(problem.File)
+		if problem.Line > 0 {
+			props += ",line=" + strconv.Itoa(problem.Line)
+		}
+		if problem.Column > 0 {
+			props += ",col=" + strconv.Itoa(problem.Column)
+		}
+		return fmt.Sprintf("::%s %s::%s", problem.Severity, props, githubEscape(problem.Message))
+	case CITeamCity:
+		status := "ERROR"
+		if problem.Severity == "warning" {
+			status = "WARNING"
+		}
+		location := problem.File
+		if problem.Line > 0 {
+			location += ":" + strconv.Itoa(problem.Line)
+		}
+		return fmt.Sprintf("##teamcity[message text='%s' status='%s']", teamcityEscape(location+": "+problem.Message), status)
+	default:
+		return ""
+	}
+}
+
+func githubEscape(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+func githubEscapeProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
+
+func teamcityEscape(s string) string {
+	return strings.NewReplacer("|", "||", "'", "|'", "\n", "|n", "\r", "|r", "[", "|[", "]", "|]").Replace(s)
+}
@@ -0,0 +1,39 @@
+//go:build (linux || darwin) && (amd64 || arm64)
+
+package exec_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/alecthomas/exec"
+)
+
+func TestAnnotateProblems(t *testing.T) {
+	script := `echo "building"; echo "main.go:12:5: undefined: foo" >&2; echo "util.c:3: warning: unused variable 'x'" >&2`
+	tests := []struct {
+		format   exec.CIFormat
+		expected string
+	}{
+		{exec.CIGitHubActions, "building\n" +
+			"main.go:12:5: undefined: foo\n::error file=main.go,line=12,col=5::undefined: foo\n" +
+			"util.c:3: warning: unused variable 'x'\n::warning file=util.c,line=3::unused variable 'x'\n"},
+		{exec.CITeamCity, "building\n" +
+			"main.go:12:5: undefined: foo\n##teamcity[message text='main.go:12: undefined: foo' status='ERROR']\n" +
+			"util.c:3: warning: unused variable 'x'\n##teamcity[message text='util.c:3: unused variable |'x|'' status='WARNING']\n"},
+		{exec.CINone, "building\nmain.go:12:5: undefined: foo\nutil.c:3: warning: unused variable 'x'\n"},
+	}
+	for _, test := range tests {
+		var output bytes.Buffer
+		cmd := exec.Command("sh", "-c", script)
+		cmd.Stdout = &output
+		cmd.Stderr = &output
+		exec.AnnotateProblems(cmd, test.format, exec.CompilerProblems)
+		if err := cmd.Run(); err != nil {
+			t.Fatal(err)
+		}
+		if output.String() != test.expected {
+			t.Errorf("Expected:\n%s\ngot:\n%s", test.expected, output.String())
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package exec
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Cmd represents an external command being prepared or run.
+//
+// It embeds the os/exec.Cmd that actually runs the intermediary, so it can be used in the same way, but Path, Args
+// and String report the command the intermediary runs rather than the intermediary itself. Path and Args may be
+// modified before the command is started.
+type Cmd struct {
+	*exec.Cmd
+
+	// Path is the path of the command to run, as for os/exec.Cmd. The intermediary resolves and runs Args[0], so
+	// Path is only used if it is changed after the command is created, in which case it also becomes the child's
+	// argv[0].
+	Path string
+	// Args holds the command line arguments, including the command as Args[0].
+	Args []string
+
+	// path is Path as it was when the command was created.
+	path string
+	// direct is true if the command is run without the intermediary, as with StrategyPdeathsig.
+	direct bool
+	// profile is the path of the profile written by the command, if it is run with Profile.
+	profile string
+	// cleanups are called once the command has been waited for, or has failed to start.
+	cleanups []func()
+}
+
+// logicalPath returns the Path that os/exec would set for a command named name.
+func logicalPath(name string) string {
+	if filepath.Base(name) == name {
+		if path, err := LookPath(name); err == nil {
+			return path
+		}
+	}
+	return name
+}
+
+// newCmd wraps std, which will run the command with the given logical path and arguments.
+func newCmd(std *exec.Cmd, path string, args []string) *Cmd {
+	return &Cmd{Cmd: std, Path: path, Args: args, path: path}
+}
+
+// Unwrap returns the underlying os/exec.Cmd, with its Path and Args set to run the command via the intermediary.
+//
+// This allows the command to be passed to code that requires an os/exec.Cmd. Later changes to the Cmd's Path and Args
+// are applied to it only if the command is started through the Cmd. Likewise, ShutdownAll and NotifyAndForward only
+// see a command as running if it is started and waited for through the Cmd.
+func (c *Cmd) Unwrap() *exec.Cmd {
+	c.syncArgs()
+	return c.Cmd
+}
+
+// String returns a human-readable description of the command, as for os/exec.Cmd.String.
+func (c *Cmd) String() string {
+	b := new(strings.Builder)
+	b.WriteString(c.Path)
+	if len(c.Args) > 1 {
+		for _, arg := range c.Args[1:] {
+			b.WriteByte(' ')
+			b.WriteString(arg)
+		}
+	}
+	return b.String()
+}
+
+// Start starts the command, as for os/exec.Cmd.Start.
+func (c *Cmd) Start() error {
+	c.syncArgs()
+	if err := c.Cmd.Start(); err != nil {
+		startFailures.Add(1)
+		c.cleanup()
+		return err
+	}
+	markStarted(c, c.Process.Pid)
+	startTimeout(c, c.Process.Pid)
+	return nil
+}
+
+// Wait waits for the command to exit, as for os/exec.Cmd.Wait.
+func (c *Cmd) Wait() error {
+	err := c.Cmd.Wait()
+	markExited(c)
+	c.cleanup()
+	if timeout, ok := timedOut(c); ok {
+		if err == nil {
+			return fmt.Errorf("timed out after %s", timeout)
+		}
+		return fmt.Errorf("timed out after %s: %w", timeout, err)
+	}
+	return err
+}
+
+// cleanup calls and discards the command's cleanups.
+func (c *Cmd) cleanup() {
+	cleanups := c.cleanups
+	c.cleanups = nil
+	for _, fn := range cleanups {
+		fn()
+	}
+}
+
+// Run starts the command and waits for it to complete, as for os/exec.Cmd.Run.
+func (c *Cmd) Run() error {
+	if err := c.Start(); err != nil {
+		return err
+	}
+	return c.Wait()
+}
+
+// maxOutputStderr is how much of stderr Output records in an *ExitError, as for os/exec.
+const maxOutputStderr = 32 << 10
+
+// Output runs the command and returns its standard output, as for os/exec.Cmd.Output.
+func (c *Cmd) Output() ([]byte, error) {
+	if c.Stdout != nil {
+		return nil, errors.New("exec: Stdout already set")
+	}
+	var stdout bytes.Buffer
+	c.Stdout = &stdout
+	var stderr *truncatingBuffer
+	if c.Stderr == nil {
+		stderr = &truncatingBuffer{max: maxOutputStderr}
+		c.Stderr = stderr
+	}
+	err := c.Run()
+	var exitErr *exec.ExitError
+	if stderr != nil && errors.As(err, &exitErr) {
+		exitErr.Stderr = stderr.Bytes()
+	}
+	return stdout.Bytes(), err
+}
+
+// CombinedOutput runs the command and returns its combined standard output and standard error, as for
+// os/exec.Cmd.CombinedOutput.
+func (c *Cmd) CombinedOutput() ([]byte, error) {
+	if c.Stdout != nil {
+		return nil, errors.New("exec: Stdout already set")
+	}
+	if c.Stderr != nil {
+		return nil, errors.New("exec: Stderr already set")
+	}
+	var output bytes.Buffer
+	c.Stdout = &output
+	c.Stderr = &output
+	err := c.Run()
+	return output.Bytes(), err
+}
+
+// truncatingBuffer records the first max bytes written to it, and discards the rest.
+type truncatingBuffer struct {
+	bytes.Buffer
+	max int
+}
+
+func (b *truncatingBuffer) Write(p []byte) (int, error) {
+	if room := b.max - b.Len(); room > 0 {
+		b.Buffer.Write(p[:min(len(p), room)])
+	}
+	return len(p), nil
+}
@@ -0,0 +1,171 @@
+//go:build (linux || darwin) && (amd64 || arm64)
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	stdexec "os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/alecthomas/exec"
+)
+
+// subcommands are dispatched when they are the first argument. Use "--" to run a command with one of these names.
+var subcommands = map[string]func(w io.Writer, args []string) int{
+	"doctor":       doctor,
+	"cache":        cache,
+	"tree":         tree,
+	"intermediary": intermediary,
+}
+
+func doctor(w io.Writer, args []string) int {
+	info := exec.Intermediary()
+	fmt.Fprintf(w, "target:        %s\n", info.Target)
+	fmt.Fprintf(w, "mechanism:     %s (poll interval %s, max latency %s)\n", info.Mechanism, info.PollInterval, info.MaxLatency)
+	if info.Emulator != "" {
+		fmt.Fprintf(w, "emulator:      %s\n", info.Emulator)
+	}
+	report := exec.SelfTest()
+	fmt.Fprintf(w, "kernel:        %s\n", report.Kernel)
+	if report.Sandbox != "" {
+		fmt.Fprintf(w, "sandbox:       %s\n", report.Sandbox)
+	}
+	fmt.Fprintf(w, "seccomp:       %v\n", report.Seccomp)
+	if stats := exec.GetStats(); stats.IntermediaryPath != "" {
+		fmt.Fprintf(w, "intermediary:  %s\n", stats.IntermediaryPath)
+		fmt.Fprintf(w, "sha256:        %s\n", exec.IntermediaryChecksum())
+	}
+	if report.ParentDeathLatency > 0 {
+		fmt.Fprintf(w, "measured:      %s from parent death to child termination\n", report.ParentDeathLatency)
+	}
+	for _, check := range report.Checks {
+		status := "ok"
+		if check.Err != nil {
+			status = "FAILED: " + check.Err.Error()
+		}
+		fmt.Fprintf(w, "check %-13s %s\n", check.Name+":", status)
+	}
+	if report.Err() != nil {
+		return 1
+	}
+	return 0
+}
+
+func cache(w io.Writer, args []string) int {
+	if len(args) != 1 || args[0] != "clean" {
+		fmt.Fprintln(os.Stderr, "usage: execguard cache clean")
+		return 2
+	}
+	removed, err := exec.CleanStale()
+	for _, path := range removed {
+		fmt.Fprintf(w, "removed %s\n", path)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "execguard: %s\n", err)
+		return 1
+	}
+	return 0
+}
+
+// intermediary installs the embedded intermediary, for use with EXEC_INTERMEDIARY_PATH.
+func intermediary(w io.Writer, args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "usage: execguard intermediary <path>")
+		return 2
+	}
+	f, err := os.OpenFile(args[0], os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0755)
+	if err == nil {
+		err = exec.WriteIntermediary(f)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "execguard: %s\n", err)
+		return 1
+	}
+	fmt.Fprintf(w, "installed %s (sha256 %s)\n", args[0], exec.IntermediaryChecksum())
+	return 0
+}
+
+func tree(w io.Writer, args []string) int {
+	flags := flag.NewFlagSet("execguard tree", flag.ContinueOnError)
+	kill := flags.Bool("kill", false, "send SIGKILL to the process and all of its descendants")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: execguard tree [-kill] <pid>")
+		return 2
+	}
+	root, err := strconv.Atoi(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "execguard: invalid pid %q\n", flags.Arg(0))
+		return 2
+	}
+	procs, err := listProcesses()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "execguard: %s\n", err)
+		return 1
+	}
+	if _, ok := procs[root]; !ok {
+		fmt.Fprintf(os.Stderr, "execguard: no such process %d\n", root)
+		return 1
+	}
+	children := map[int][]int{}
+	for pid, proc := range procs {
+		children[proc.ppid] = append(children[proc.ppid], pid)
+	}
+	var order []int
+	var walk func(pid, depth int)
+	walk = func(pid, depth int) {
+		fmt.Fprintf(w, "%s%d %s\n", strings.Repeat("  ", depth), pid, procs[pid].command)
+		order = append(order, pid)
+		for _, child := range children[pid] {
+			walk(child, depth+1)
+		}
+	}
+	walk(root, 0)
+	if *kill {
+		// Kill the root first so it cannot spawn replacements for descendants as they die.
+		for _, pid := range order {
+			_ = syscall.Kill(pid, syscall.SIGKILL)
+		}
+	}
+	return 0
+}
+
+type process struct {
+	ppid    int
+	command string
+}
+
+// listProcesses uses ps(1), which has a common format across Linux and macOS.
+func listProcesses() (map[int]process, error) {
+	output, err := stdexec.Command("ps", "-A", "-o", "pid=,ppid=,command=").Output()
+	if err != nil {
+		return nil, err
+	}
+	procs := map[int]process{}
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		pid, err1 := strconv.Atoi(fields[0])
+		ppid, err2 := strconv.Atoi(fields[1])
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		procs[pid] = process{ppid: ppid, command: strings.Join(fields[2:], " ")}
+	}
+	return procs, scanner.Err()
+}
@@ -0,0 +1,70 @@
+//go:build (linux || darwin) && (amd64 || arm64)
+
+package main
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	stdexec "os/exec"
+
+	"github.com/alecthomas/exec"
+)
+
+func TestDoctor(t *testing.T) {
+	var output strings.Builder
+	if code := doctor(&output, nil); code != 0 {
+		t.Errorf("Expected doctor to succeed, got %d:\n%s", code, output.String())
+	}
+	if !strings.Contains(output.String(), "check parent-death: ok") {
+		t.Errorf("Expected parent-death check in output:\n%s", output.String())
+	}
+}
+
+func TestTree(t *testing.T) {
+	cmd := exec.Command("sh", "-c", "sleep 10 & wait")
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	}()
+
+	var output strings.Builder
+	var code int
+	// Retry until the shell has started its child.
+	for range 50 {
+		output.Reset()
+		code = tree(&output, []string{strconv.Itoa(cmd.Process.Pid)})
+		if strings.Contains(output.String(), "sleep 10") {
+			break
+		}
+	}
+	if code != 0 || !strings.Contains(output.String(), "sleep 10") {
+		t.Fatalf("Expected tree to include sleep, got %d:\n%s", code, output.String())
+	}
+
+	if code := tree(&output, []string{"-kill", strconv.Itoa(cmd.Process.Pid)}); code != 0 {
+		t.Errorf("Expected tree -kill to succeed, got %d", code)
+	}
+	if err := cmd.Wait(); err == nil {
+		t.Error("Expected killed command to fail")
+	}
+}
+
+func TestIntermediary(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "intermediary")
+	var output strings.Builder
+	if code := intermediary(&output, []string{path}); code != 0 {
+		t.Fatalf("Expected intermediary to succeed, got %d", code)
+	}
+	if !strings.Contains(output.String(), exec.IntermediaryChecksum()) {
+		t.Errorf("Expected checksum in output, got %q", output.String())
+	}
+	if err := stdexec.Command(path, "true").Run(); err != nil {
+		t.Errorf("Expected the installed intermediary to run: %v", err)
+	}
+}
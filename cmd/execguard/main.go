@@ -0,0 +1,131 @@
+//go:build (linux || darwin) && (amd64 || arm64)
+
+// Command execguard runs a command with the guarantees of github.com/alecthomas/exec: the command, and every process
+// in its process group, is terminated if execguard or the process that started execguard dies.
+//
+//	execguard [flags] -- command [args...]
+//	execguard doctor                  # check that the guarantees hold on this host
+//	execguard cache clean             # remove intermediaries left behind by dead processes
+//	execguard tree [-kill] <pid>      # show (or kill) a process and its descendants
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/alecthomas/exec"
+)
+
+// exitTimeout is the exit status used when the command is terminated by -timeout, matching timeout(1).
+const exitTimeout = 124
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+func run(args []string) int {
+	if len(args) > 0 {
+		if subcommand, ok := subcommands[args[0]]; ok {
+			return subcommand(os.Stdout, args[1:])
+		}
+	}
+	flags := flag.NewFlagSet("execguard", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: execguard [flags] -- command [args...]\n")
+		fmt.Fprintf(flags.Output(), "       execguard doctor | cache clean | tree [-kill] <pid>\n\n")
+		fmt.Fprintf(flags.Output(), "Runs command, terminating its whole process tree if execguard or its parent dies.\n\n")
+		flags.PrintDefaults()
+	}
+	grace := flags.Duration("grace", 5*time.Second, "time to wait after SIGTERM before sending SIGKILL")
+	timeout := flags.Duration("timeout", 0, "terminate the command after this long (0 for no timeout)")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	exec.NotifyAndForward(ctx)
+
+	cmd := exec.Command(flags.Arg(0), flags.Args()[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	exec.SetGracePeriod(cmd, *grace)
+	if err := cmd.Start(); err != nil {
+		fmt.Fprintf(os.Stderr, "execguard: %s\n", err)
+		return 127
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	var timedOut <-chan time.Time
+	if *timeout > 0 {
+		timer := time.NewTimer(*timeout)
+		defer timer.Stop()
+		timedOut = timer.C
+	}
+	orphaned := watchParent(ctx)
+
+	select {
+	case err := <-done:
+		return exitCode(err)
+	case <-timedOut:
+		fmt.Fprintf(os.Stderr, "execguard: timed out after %s\n", *timeout)
+		shutdown(done, *grace)
+		return exitTimeout
+	case <-orphaned:
+		shutdown(done, *grace)
+		return 1
+	}
+}
+
+// shutdown terminates the command and waits for Wait to return.
+func shutdown(done <-chan error, grace time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), grace)
+	defer cancel()
+	exec.ShutdownAll(ctx)
+	<-done
+}
+
+// watchParent returns a channel that is closed when the process that started execguard exits.
+func watchParent(ctx context.Context) <-chan struct{} {
+	orphaned := make(chan struct{})
+	parent := os.Getppid()
+	go func() {
+		ticker := time.NewTicker(exec.Intermediary().PollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if os.Getppid() != parent {
+					close(orphaned)
+					return
+				}
+			}
+		}
+	}()
+	return orphaned
+}
+
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() >= 0 {
+		return exitErr.ExitCode()
+	}
+	fmt.Fprintf(os.Stderr, "execguard: %s\n", err)
+	return 1
+}
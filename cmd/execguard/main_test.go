@@ -0,0 +1,31 @@
+//go:build (linux || darwin) && (amd64 || arm64)
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRunExitCode(t *testing.T) {
+	if code := run([]string{"--", "sh", "-c", "exit 3"}); code != 3 {
+		t.Errorf("Expected exit code 3, got %d", code)
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	start := time.Now()
+	code := run([]string{"-timeout", "200ms", "-grace", "100ms", "--", "sleep", "10"})
+	if code != exitTimeout {
+		t.Errorf("Expected exit code %d, got %d", exitTimeout, code)
+	}
+	if duration := time.Since(start); duration > 5*time.Second {
+		t.Errorf("Timeout took too long: %v", duration)
+	}
+}
+
+func TestRunUsage(t *testing.T) {
+	if code := run(nil); code != 2 {
+		t.Errorf("Expected usage exit code 2, got %d", code)
+	}
+}
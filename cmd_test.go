@@ -0,0 +1,65 @@
+//go:build (linux || darwin) && (amd64 || arm64)
+
+package exec_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/exec"
+)
+
+func TestCmdReportsLogicalCommand(t *testing.T) {
+	echo, err := exec.LookPath("echo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command("echo", "hello")
+	if cmd.Path != echo {
+		t.Errorf("Expected Path %q, got %q", echo, cmd.Path)
+	}
+	if expected := echo + " hello"; cmd.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, cmd.String())
+	}
+
+	cmd.Args = []string{"echo", "changed"}
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(output) != "changed\n" {
+		t.Errorf("Expected changes to Args to be applied, got %q", output)
+	}
+}
+
+func TestCmdChangedPath(t *testing.T) {
+	path, err := exec.LookPath("true")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command("false")
+	cmd.Path = path
+	if err := cmd.Run(); err != nil {
+		t.Errorf("Expected changed Path to be run, got %v", err)
+	}
+}
+
+func TestCmdOutputRecordsStderr(t *testing.T) {
+	_, err := exec.Command("sh", "-c", "echo failed >&2; exit 3").Output()
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		t.Fatalf("Expected an ExitError, got %v", err)
+	}
+	if string(exitErr.Stderr) != "failed\n" {
+		t.Errorf("Expected %q, got %q", "failed\n", exitErr.Stderr)
+	}
+	if _, err := exec.Command("true").Output(); err != nil {
+		t.Error(err)
+	}
+	cmd := exec.Command("true")
+	cmd.Stdout = &strings.Builder{}
+	if _, err := cmd.CombinedOutput(); err == nil {
+		t.Error("Expected CombinedOutput to fail with Stdout already set")
+	}
+}
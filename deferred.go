@@ -0,0 +1,54 @@
+package exec
+
+import (
+	"fmt"
+	"sync"
+)
+
+// DeferredRunner records commands to be run later, so that a plan can be built up, shown to the user, and then
+// applied. The zero value is ready to use, and it is safe for concurrent use.
+type DeferredRunner struct {
+	lock sync.Mutex
+	cmds []*Cmd
+}
+
+// Add cmd to the end of the plan. It must not have been started.
+func (d *DeferredRunner) Add(cmd *Cmd) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.cmds = append(d.cmds, cmd)
+}
+
+// Plan returns the pending commands in the order they will be run, rendered with Cmd.ShellString.
+func (d *DeferredRunner) Plan() []string {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	plan := make([]string, len(d.cmds))
+	for i, cmd := range d.cmds {
+		plan[i] = cmd.ShellString()
+	}
+	return plan
+}
+
+// Discard all pending commands without running them.
+func (d *DeferredRunner) Discard() {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.cmds = nil
+}
+
+// Flush runs the pending commands in order, stopping at the first that fails, and removes them from the plan.
+//
+// Commands after a failure are discarded. The error identifies the failed command by its position in the plan.
+func (d *DeferredRunner) Flush() error {
+	d.lock.Lock()
+	cmds := d.cmds
+	d.cmds = nil
+	d.lock.Unlock()
+	for i, cmd := range cmds {
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("step %d (%s): %w", i+1, cmd.ShellString(), err)
+		}
+	}
+	return nil
+}
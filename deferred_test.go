@@ -0,0 +1,51 @@
+//go:build (linux || darwin) && (amd64 || arm64)
+
+package exec_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/exec"
+)
+
+func TestDeferredRunner(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "out")
+	var runner exec.DeferredRunner
+	for _, word := range []string{"one", "two"} {
+		cmd := exec.Command("sh", "-c", `echo "$0" >> "$1"`, word, file)
+		runner.Add(cmd)
+	}
+	runner.Add(exec.Command("false"))
+	runner.Add(exec.Command("sh", "-c", `echo three >> "$0"`, file))
+
+	falsePath, err := exec.LookPath("false")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan := runner.Plan()
+	if len(plan) != 4 || !strings.Contains(plan[0], "one") || plan[2] != falsePath {
+		t.Errorf("Unexpected plan %q", plan)
+	}
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Fatal("Expected nothing to run before Flush")
+	}
+
+	err = runner.Flush()
+	if err == nil || !strings.HasPrefix(err.Error(), "step 3 ("+falsePath+"): ") {
+		t.Errorf("Expected step 3 to fail, got %v", err)
+	}
+	output, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(output) != "one\ntwo\n" {
+		t.Errorf("Expected steps to run in order until the failure, got %q", output)
+	}
+	if len(runner.Plan()) != 0 {
+		t.Error("Expected plan to be empty after Flush")
+	}
+}
@@ -0,0 +1,65 @@
+package exec
+
+import (
+	"errors"
+	"os"
+)
+
+// DirectFDs replaces any of the command's Stdin, Stdout and Stderr that are network connections, such as a
+// *net.TCPConn or *net.UnixConn, with a duplicate of the connection's file descriptor. It must be called after they
+// are set and before Start. Call the returned function once the command has started to close the duplicates.
+//
+// os/exec passes an *os.File to the child directly, and the intermediary passes it on unchanged, so the child reads
+// and writes it with no copying by the parent. Any other value, including a network connection, is connected to the
+// child with a pipe and a goroutine in the parent that copies to or from it. DirectFDs gives connections the direct
+// path too.
+func DirectFDs(cmd *Cmd) (release func() error, err error) {
+	var files []*os.File
+	release = func() error {
+		var errs []error
+		for _, f := range files {
+			errs = append(errs, f.Close())
+		}
+		return errors.Join(errs...)
+	}
+	dup := func(v any) (*os.File, error) {
+		conn, ok := v.(interface{ File() (*os.File, error) })
+		if !ok {
+			return nil, nil
+		}
+		f, err := conn.File()
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+		return f, nil
+	}
+	same := cmd.Stdout == cmd.Stderr
+	stdin, err := dup(cmd.Stdin)
+	if err != nil {
+		_ = release()
+		return nil, err
+	}
+	stdout, err := dup(cmd.Stdout)
+	if err != nil {
+		_ = release()
+		return nil, err
+	}
+	stderr := stdout
+	if !same {
+		if stderr, err = dup(cmd.Stderr); err != nil {
+			_ = release()
+			return nil, err
+		}
+	}
+	if stdin != nil {
+		cmd.Stdin = stdin
+	}
+	if stdout != nil {
+		cmd.Stdout = stdout
+	}
+	if stderr != nil {
+		cmd.Stderr = stderr
+	}
+	return release, nil
+}
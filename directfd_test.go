@@ -0,0 +1,54 @@
+//go:build (linux || darwin) && (amd64 || arm64)
+
+package exec_test
+
+import (
+	"io"
+	"net"
+	"os"
+	"testing"
+
+	"github.com/alecthomas/exec"
+)
+
+func TestDirectFDs(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close() //nolint
+	client, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close() //nolint
+	server, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command("echo", "hello")
+	cmd.Stdout = server
+	release, err := exec.DirectFDs(cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cmd.Stdout.(*os.File); !ok {
+		t.Fatalf("Expected Stdout to be replaced with an *os.File, got %T", cmd.Stdout)
+	}
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if err := release(); err != nil {
+		t.Fatal(err)
+	}
+	_ = server.Close()
+
+	output, err := io.ReadAll(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(output) != "hello\n" {
+		t.Errorf("Expected %q, got %q", "hello\n", string(output))
+	}
+}
@@ -0,0 +1,6 @@
+package exec
+
+import "embed"
+
+//go:embed intermediary/intermediary-x86_64-linux.gz
+var binaries embed.FS
@@ -0,0 +1,134 @@
+package exec
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// binfmtDir is where Linux registers interpreters for binary formats with binfmt_misc.
+const binfmtDir = "/proc/sys/fs/binfmt_misc"
+
+// binfmtHeaderSize is the maximum number of bytes binfmt_misc matches against.
+const binfmtHeaderSize = 128
+
+// EmulatorRosetta is reported by Emulator for x86-64 binaries run by Rosetta on Apple Silicon.
+const EmulatorRosetta = "rosetta"
+
+// Emulator returns the emulator that will transparently run the command's binary, or "" if it runs natively or is
+// not an ELF or Mach-O executable.
+//
+// On Linux this is the interpreter registered with binfmt_misc for binaries built for another architecture, typically
+// qemu-user. On Apple Silicon it is EmulatorRosetta for a binary with no arm64 code. Emulation is commonly an order of
+// magnitude slower than native execution, so it is worth reporting, eg. in CI.
+func Emulator(cmd *Cmd) string {
+	if !filepath.IsAbs(cmd.Path) {
+		return ""
+	}
+	return emulator(cmd.Path, binaryArchitectures(cmd.Path))
+}
+
+// RequireNative returns an *ArchitectureError if the command's binary cannot run natively on the host, including when
+// it would be run transparently by an emulator, in which case the error's Emulator is set.
+func RequireNative(cmd *Cmd) error {
+	if !filepath.IsAbs(cmd.Path) {
+		return nil
+	}
+	archs := binaryArchitectures(cmd.Path)
+	if len(archs) == 0 || slices.ContainsFunc(archs, nativeArch) {
+		return nil
+	}
+	return &ArchitectureError{Path: cmd.Path, Binary: archs, Host: runtime.GOARCH, Emulator: emulator(cmd.Path, archs)}
+}
+
+// emulator returns the emulator that will run the binary at path, built for archs, or "".
+func emulator(path string, archs []string) string {
+	if len(archs) == 0 || slices.ContainsFunc(archs, nativeArch) {
+		return ""
+	}
+	if runtime.GOOS == "darwin" && runtime.GOARCH == "arm64" && slices.Contains(archs, "amd64") {
+		return EmulatorRosetta
+	}
+	return binfmtInterpreter(path)
+}
+
+// binfmtInterpreter returns the interpreter of the enabled binfmt_misc entry whose magic matches the file at path, or
+// "".
+func binfmtInterpreter(path string) string {
+	if status, err := os.ReadFile(filepath.Join(binfmtDir, "status")); err != nil || strings.TrimSpace(string(status)) != "enabled" {
+		return ""
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	header := make([]byte, binfmtHeaderSize)
+	n, _ := io.ReadFull(f, header)
+	_ = f.Close()
+	header = header[:n]
+
+	entries, err := os.ReadDir(binfmtDir)
+	if err != nil {
+		return ""
+	}
+	for _, entry := range entries {
+		if name := entry.Name(); name == "status" || name == "register" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(binfmtDir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		if interpreter, ok := matchBinfmt(data, header); ok {
+			return interpreter
+		}
+	}
+	return ""
+}
+
+// matchBinfmt parses a binfmt_misc entry, and returns its interpreter if it is enabled and its magic matches header.
+func matchBinfmt(entry, header []byte) (string, bool) {
+	var (
+		enabled     bool
+		interpreter string
+		offset      int
+		magic, mask []byte
+	)
+	scanner := bufio.NewScanner(bytes.NewReader(entry))
+	for scanner.Scan() {
+		key, value, _ := strings.Cut(scanner.Text(), " ")
+		switch key {
+		case "enabled":
+			enabled = true
+		case "interpreter":
+			interpreter = value
+		case "offset":
+			offset, _ = strconv.Atoi(value)
+		case "magic":
+			magic, _ = hex.DecodeString(value)
+		case "mask":
+			mask, _ = hex.DecodeString(value)
+		}
+	}
+	// Entries matched by file extension rather than magic have no magic line.
+	if !enabled || len(magic) == 0 || offset < 0 || offset+len(magic) > len(header) {
+		return "", false
+	}
+	for i, b := range magic {
+		m := byte(0xff)
+		if i < len(mask) {
+			m = mask[i]
+		}
+		if header[offset+i]&m != b&m {
+			return "", false
+		}
+	}
+	return interpreter, true
+}
@@ -0,0 +1,102 @@
+package exec
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// EnvChangeKind describes how an environment variable differs from the parent environment.
+type EnvChangeKind int
+
+const (
+	EnvAdded EnvChangeKind = iota
+	EnvRemoved
+	EnvChanged
+)
+
+func (k EnvChangeKind) String() string {
+	switch k {
+	case EnvAdded:
+		return "added"
+	case EnvRemoved:
+		return "removed"
+	case EnvChanged:
+		return "changed"
+	default:
+		return fmt.Sprintf("EnvChangeKind(%d)", int(k))
+	}
+}
+
+// EnvChange is a single difference between a command's environment and the parent environment.
+type EnvChange struct {
+	Kind EnvChangeKind `json:"kind"`
+	Name string        `json:"name"`
+	Old  string        `json:"old,omitempty"`
+	New  string        `json:"new,omitempty"`
+}
+
+func (e EnvChange) String() string {
+	switch e.Kind {
+	case EnvAdded:
+		return "+" + e.Name + "=" + e.New
+	case EnvRemoved:
+		return "-" + e.Name + "=" + e.Old
+	default:
+		return "~" + e.Name + "=" + e.Old + " -> " + e.New
+	}
+}
+
+// EnvError describes a problem with one of a command's environment variables, as reported by Validate.
+type EnvError struct {
+	// Name of the variable, or the whole entry if it has no '='.
+	Name string
+	// Index of the entry in the command's Env.
+	Index  int
+	Reason string
+}
+
+func (e *EnvError) Error() string {
+	return fmt.Sprintf("environment variable %s %s", e.Name, e.Reason)
+}
+
+// EnvDiff reports which environment variables cmd will add, remove or change relative to os.Environ(), sorted by
+// name.
+//
+// The comparison is made against the current process environment, so call it immediately before or after Start to
+// capture what the child actually received. A nil cmd.Env inherits the parent environment and so has no changes.
+func EnvDiff(cmd *Cmd) []EnvChange {
+	if cmd.Env == nil {
+		return nil
+	}
+	parent := envMap(os.Environ())
+	child := envMap(cmd.Env)
+	var changes []EnvChange
+	for name, value := range child {
+		old, ok := parent[name]
+		switch {
+		case !ok:
+			changes = append(changes, EnvChange{Kind: EnvAdded, Name: name, New: value})
+		case old != value:
+			changes = append(changes, EnvChange{Kind: EnvChanged, Name: name, Old: old, New: value})
+		}
+	}
+	for name, value := range parent {
+		if _, ok := child[name]; !ok {
+			changes = append(changes, EnvChange{Kind: EnvRemoved, Name: name, Old: value})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Name < changes[j].Name })
+	return changes
+}
+
+// envMap converts an environment list to a map. As with os/exec, later duplicates win.
+func envMap(env []string) map[string]string {
+	out := make(map[string]string, len(env))
+	for _, kv := range env {
+		name, value, _ := strings.Cut(kv, "=")
+		out[name] = value
+	}
+	return out
+}
@@ -0,0 +1,42 @@
+//go:build (linux || darwin) && (amd64 || arm64)
+
+package exec_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/alecthomas/exec"
+)
+
+func TestEnvDiff(t *testing.T) {
+	t.Setenv("EXEC_TEST_CHANGED", "old")
+	t.Setenv("EXEC_TEST_REMOVED", "gone")
+
+	cmd := exec.Command("true")
+	if changes := exec.EnvDiff(cmd); changes != nil {
+		t.Errorf("Expected no changes for inherited environment, got %v", changes)
+	}
+
+	for _, kv := range os.Environ() {
+		if kv != "EXEC_TEST_CHANGED=old" && kv != "EXEC_TEST_REMOVED=gone" {
+			cmd.Env = append(cmd.Env, kv)
+		}
+	}
+	cmd.Env = append(cmd.Env, "EXEC_TEST_CHANGED=new", "EXEC_TEST_ADDED=value")
+
+	changes := exec.EnvDiff(cmd)
+	expected := []string{
+		"+EXEC_TEST_ADDED=value",
+		"~EXEC_TEST_CHANGED=old -> new",
+		"-EXEC_TEST_REMOVED=gone",
+	}
+	if len(changes) != len(expected) {
+		t.Fatalf("Expected %d changes, got %v", len(expected), changes)
+	}
+	for i, change := range changes {
+		if change.String() != expected[i] {
+			t.Errorf("Expected change %d to be %q, got %q", i, expected[i], change.String())
+		}
+	}
+}
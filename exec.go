@@ -1,80 +1,222 @@
 //go:build (linux || darwin) && (amd64 || arm64)
 
-// Package exec is identical to os/exec except that it guarantees that subprocesses will terminate when their parent
-// does.
-//
-// It achieves this by embedding a tiny C binary that is launched as an intermediary, watches the parent PID for
-// termination, then terminates the child.
 package exec
 
 import (
 	"compress/gzip"
 	"context"
-	"embed"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"runtime"
 	"sync"
+	"syscall"
 )
 
 var (
-	//go:embed intermediary/*.gz
-	binaries      embed.FS
-	extracted     sync.Once
+	// source defaults to binaries, which embeds only the intermediary for the target platform (see embed_*.go).
+	source        fs.FS = binaries
+	extractDir    string
+	adHocSign     bool
+	installedPath string
+
+	// extractMu guards extractDone, extractedPath and extractErr.
+	extractMu sync.Mutex
+	// extractDone is set once extraction has been attempted, until Cleanup is called.
+	extractDone   bool
 	extractedPath string
+	extractErr    error
 )
 
-type Cmd = exec.Cmd
-type Error = exec.Error
-type ExitError = exec.ExitError
+// Supported is true if guaranteed subprocess termination is available on the target platform.
+const Supported = true
 
-var targetMap = map[string]string{
-	"arm64-linux":  "aarch64-linux",
-	"amd64-linux":  "x86_64-linux",
-	"arm64-darwin": "aarch64-macos",
-	"amd64-darwin": "x86_64-macos",
+// CommandContext is like os/exec.CommandContext, but runs the command via the intermediary.
+//
+// It panics if the intermediary cannot be extracted. Use TryCommandContext to handle that case.
+func CommandContext(ctx context.Context, name string, arg ...string) *Cmd {
+	cmd, err := TryCommandContext(ctx, name, arg...)
+	if err != nil {
+		panic(err)
+	}
+	return cmd
 }
 
-var (
-	ErrDot       = exec.ErrDot
-	ErrNotFound  = exec.ErrNotFound
-	ErrWaitDelay = exec.ErrWaitDelay
-)
-
-func CommandContext(ctx context.Context, name string, arg ...string) *Cmd {
-	// Extract the intermediary binary to a temporary file on first use
-	extracted.Do(func() {
-		if err := extractBinary(); err != nil {
-			panic(err)
+// TryCommandContext is like CommandContext, but returns an error wrapping ErrExtractFailed if the intermediary cannot
+// be extracted, rather than panicking. Callers may then fall back to os/exec.
+func TryCommandContext(ctx context.Context, name string, arg ...string) (*Cmd, error) {
+	args := append([]string{name}, arg...)
+	var cmd *Cmd
+	if strategy == StrategyPdeathsig {
+		std := exec.CommandContext(ctx, name, arg...)
+		cmd = newCmd(std, std.Path, args)
+		cmd.direct = true
+	} else {
+		path, err := extract()
+		if err != nil {
+			return nil, err
 		}
-	})
-	cmd := exec.CommandContext(ctx, extractedPath, append([]string{name}, arg...)...)
-	cmd.Args[0] = "watchdog"
-	return cmd
+		std := exec.CommandContext(ctx, path, args...)
+		std.Args[0] = "watchdog"
+		cmd = newCmd(std, logicalPath(name), args)
+	}
+	wrapCancel(cmd)
+	setpgid(cmd)
+	register(cmd)
+	return cmd, nil
 }
 
+// Command is like os/exec.Command, but runs the command via the intermediary.
+//
+// It panics if the intermediary cannot be extracted. Use TryCommand to handle that case.
 func Command(name string, arg ...string) *Cmd {
 	return CommandContext(context.Background(), name, arg...)
 }
 
-func LookPath(file string) (string, error) {
-	return exec.LookPath(file)
+// TryCommand is like Command, but returns an error wrapping ErrExtractFailed if the intermediary cannot be extracted.
+func TryCommand(name string, arg ...string) (*Cmd, error) {
+	return TryCommandContext(context.Background(), name, arg...)
 }
 
-func extractBinary() error {
-	w, err := os.CreateTemp("", "")
+// NewFromStd converts a command created with os/exec so that it runs via the intermediary, and returns it.
+//
+// The command is modified in place, and must not have been started. It panics if the intermediary cannot be
+// extracted. Use TryNewFromStd to handle that case.
+func NewFromStd(std *exec.Cmd) *Cmd {
+	cmd, err := TryNewFromStd(std)
 	if err != nil {
-		return err
+		panic(err)
 	}
-	defer w.Close() //nolint
+	return cmd
+}
+
+// TryNewFromStd is like NewFromStd, but returns an error wrapping ErrExtractFailed if the intermediary cannot be
+// extracted, rather than panicking. The command is then left unmodified, so callers may still run it with os/exec.
+func TryNewFromStd(std *exec.Cmd) (*Cmd, error) {
+	args := append([]string(nil), std.Args...)
+	if len(args) == 0 {
+		args = []string{std.Path}
+	}
+	if strategy == StrategyPdeathsig {
+		cmd := newCmd(std, std.Path, args)
+		cmd.direct = true
+		wrapCancel(cmd)
+		setpgid(cmd)
+		register(cmd)
+		return cmd, nil
+	}
+	path, err := extract()
+	if err != nil {
+		return nil, err
+	}
+	cmd := newCmd(std, std.Path, args)
+	wrapCancel(cmd)
+	if filepath.Base(std.Path) != filepath.Base(args[0]) {
+		// Path was not derived from Args[0], so the intermediary must run Path.
+		cmd.path = ""
+	}
+	std.Path = path
+	cmd.syncArgs()
+	setpgid(cmd)
+	register(cmd)
+	return cmd, nil
+}
+
+// wrapCancel marks cmd as signalled before its context's Cancel function runs, so that CheckWatchdog does not mistake
+// a cancellation for the watchdog dying. Commands created without a context have no Cancel function to wrap.
+func wrapCancel(cmd *Cmd) {
+	cancel := cmd.Cancel
+	if cancel == nil {
+		return
+	}
+	cmd.Cancel = func() error {
+		markSignalled(cmd)
+		return cancel()
+	}
+}
+
+// setpgid places the intermediary in its own process group before it execs. The intermediary does this itself, but
+// doing it at fork time means the group is guaranteed to exist by the time Start returns.
+//
+// A command run directly by StrategyPdeathsig leads its own group in the same way, and is also given a parent-death
+// signal.
+func setpgid(cmd *Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+	if cmd.direct {
+		setPdeathsig(cmd.SysProcAttr)
+	}
+}
+
+// signalGroup sends sig to the process group led by the intermediary with the given pid, which includes the child.
+func signalGroup(pid int, sig syscall.Signal) error {
+	return syscall.Kill(-pid, sig)
+}
+
+// syncArgs sets the arguments of the underlying command to run the command via the intermediary.
+func (c *Cmd) syncArgs() {
+	if c.direct {
+		if c.Path != c.path {
+			c.Cmd.Path = c.Path
+		}
+		c.Cmd.Args = c.Args
+		return
+	}
+	name := c.Path
+	if c.Path == c.path && len(c.Args) > 0 {
+		name = c.Args[0]
+	}
+	args := []string{"watchdog", name}
+	if len(c.Args) > 1 {
+		args = append(args, c.Args[1:]...)
+	}
+	c.Cmd.Args = args
+}
+
+// SetExtractDir sets the preferred directory to extract the intermediary into, ahead of the default locations.
+//
+// This is primarily useful on Android, where there is no /tmp and apps must use their private files or cache
+// directory. It must be called before the first command is created.
+func SetExtractDir(dir string) {
+	extractDir = dir
+}
+
+// intermediaryPathEnv names the environment variable that, like SetIntermediaryPath, sets the path of a pre-installed
+// intermediary.
+const intermediaryPathEnv = "EXEC_INTERMEDIARY_PATH"
 
+// SetIntermediaryPath sets the path of a pre-installed intermediary to use rather than extracting one, for
+// environments such as distroless containers and read-only root filesystems that cannot create executable files. It
+// can also be set with the EXEC_INTERMEDIARY_PATH environment variable.
+//
+// The intermediary must be identical to the one embedded in this package, as written by WriteIntermediary or
+// "execguard intermediary", so that a binary from a different version is rejected rather than misbehaving. It must
+// be called before the first command is created.
+func SetIntermediaryPath(path string) {
+	installedPath = path
+}
+
+// preinstalledPath returns the path of the pre-installed intermediary, or "" if none has been set.
+func preinstalledPath() string {
+	if installedPath != "" {
+		return installedPath
+	}
+	return os.Getenv(intermediaryPathEnv)
+}
+
+// WriteIntermediary writes the intermediary embedded for the current platform to w, eg. to pre-install it for
+// SetIntermediaryPath.
+func WriteIntermediary(w io.Writer) error {
 	target, ok := targetMap[runtime.GOARCH+"-"+runtime.GOOS]
 	if !ok {
-		return fmt.Errorf("unsupported architecture %s-%s", runtime.GOARCH, runtime.GOOS)
+		return ErrUnsupported
 	}
-
 	r, err := binaries.Open("intermediary/intermediary-" + target + ".gz")
 	if err != nil {
 		return err
@@ -84,15 +226,208 @@ func extractBinary() error {
 	if err != nil {
 		return err
 	}
-
 	_, err = io.Copy(w, gzr)
-	if err != nil {
-		return err
+	return err
+}
+
+// SetAdHocSign sets whether the intermediary is ad-hoc code signed after extraction on macOS, where the code signing
+// policy of a hardened or sandboxed app may otherwise prevent it from running. It has no effect on other platforms.
+//
+// It must be called before the first command is created.
+func SetAdHocSign(enabled bool) {
+	adHocSign = enabled
+}
+
+// extract the intermediary binary on first use, and return its path.
+func extract() (string, error) {
+	extractMu.Lock()
+	defer extractMu.Unlock()
+	if !extractDone {
+		extractedPath, extractErr = extractBinary()
+		extractDone = true
+		if extractErr != nil {
+			extractFailures.Add(1)
+		}
 	}
-	err = w.Chmod(0700)
-	if err != nil {
+	return extractedPath, extractErr
+}
+
+// Cleanup removes the extracted intermediary, so that long-running services and test suites do not leave it behind.
+// An intermediary reused from the per-user cache, or pre-installed, is left in place.
+//
+// Commands that have already started are unaffected, and the next command created extracts the intermediary again,
+// retrying if extraction previously failed. Commands that have been created but not started fail to start, so it
+// must not be called concurrently with creating or starting commands.
+func Cleanup() error {
+	extractMu.Lock()
+	defer extractMu.Unlock()
+	if !extractDone {
+		return nil
+	}
+	path, err := extractedPath, extractErr
+	extractDone, extractedPath, extractErr = false, "", nil
+	// A cached intermediary may be in use by other processes.
+	if err != nil || closeMemfd() || isCached(path) || path == preinstalledPath() {
+		return nil
+	}
+	return os.Remove(path)
+}
+
+// extractDirs returns candidate directories for the intermediary, in order of preference.
+//
+// Mandatory access control policies (SELinux, AppArmor) and noexec mounts commonly forbid executing from the system
+// temporary directory, so per-user locations are tried as fallbacks.
+func extractDirs() []string {
+	var dirs []string
+	if extractDir != "" {
+		dirs = append(dirs, extractDir)
+	}
+	dirs = append(dirs, os.TempDir())
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		dirs = append(dirs, dir)
+	}
+	if dir, err := os.UserCacheDir(); err == nil {
+		dirs = append(dirs, filepath.Join(dir, "go-exec"))
+	}
+	return dirs
+}
+
+func extractBinary() (string, error) {
+	target, ok := targetMap[runtime.GOARCH+"-"+runtime.GOOS]
+	if !ok {
+		return "", fmt.Errorf("%w: unsupported architecture %s-%s", ErrExtractFailed, runtime.GOARCH, runtime.GOOS)
+	}
+	// A pre-installed intermediary is used or rejected, but never replaced by extracting another.
+	if path := preinstalledPath(); path != "" {
+		if err := checkPreinstalled(path, target); err != nil {
+			return "", fmt.Errorf("%w: %w", ErrExtractFailed, err)
+		}
+		return path, nil
+	}
+	var errs []error
+	// Unless a directory has been requested, the intermediary is held in memory on Linux, and otherwise reused from
+	// the per-user cache.
+	if extractDir == "" {
+		if memfdSupported {
+			path, err := extractMemfd(source, target)
+			if err == nil {
+				if err = verifyIntermediary(path, target); err != nil {
+					closeMemfd()
+				}
+			}
+			if err == nil {
+				return path, nil
+			}
+			errs = append(errs, err)
+		}
+		path, fresh, err := extractCached(source, target)
+		if err == nil {
+			err = checkExtracted(path, target, fresh)
+		}
+		if err == nil {
+			return path, nil
+		}
+		errs = append(errs, err)
+	}
+	for _, dir := range extractDirs() {
+		_, _ = cleanStaleIn(dir)
+		path, err := Extract(source, target, dir)
+		if err == nil {
+			err = checkExtracted(path, target, true)
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		return path, nil
+	}
+	return "", fmt.Errorf("%w: %w", ErrExtractFailed, errors.Join(errs...))
+}
+
+// checkPreinstalled checks that the pre-installed intermediary at path is executable, and is the intermediary for
+// target embedded in this package, whatever source is set for extraction.
+func checkPreinstalled(path, target string) error {
+	if err := syscall.Access(path, accessExecute); err != nil {
+		return fmt.Errorf("%s: cannot execute: %w", path, err)
+	}
+	if err := verifyChecksum(path, target); err != nil {
+		return fmt.Errorf("%w: it is not the intermediary from this version of the package", err)
+	}
+	return nil
+}
+
+// checkExtracted checks that the intermediary for target at path can be executed, and removes it if not. Newly
+// extracted copies are also verified and prepared for execution.
+func checkExtracted(path, target string, fresh bool) error {
+	// access(2) applies mount flags and MAC policy for execute permission, so it detects most denials up front.
+	if err := syscall.Access(path, accessExecute); err != nil {
+		_ = os.Remove(path)
+		err = fmt.Errorf("%s: cannot execute: %w", path, err)
+		if policy := execPolicy(filepath.Dir(path)); policy != "" {
+			err = fmt.Errorf("%w (denied by %s)", err, policy)
+		}
 		return err
 	}
-	extractedPath = w.Name()
+	if fresh {
+		err := verifyIntermediary(path, target)
+		if err == nil {
+			err = prepareExtracted(path)
+		}
+		if err != nil {
+			_ = os.Remove(path)
+			return err
+		}
+	}
 	return nil
 }
+
+// SetIntermediarySource sets the filesystem the intermediary is extracted from, in place of the embedded binaries.
+//
+// fsys must contain intermediary/intermediary-<target>.gz, where target is as reported by Intermediary(). It must be
+// called before the first command is created.
+func SetIntermediarySource(fsys fs.FS) {
+	source = fsys
+}
+
+// Extract decompresses the intermediary for target (eg. "x86_64-linux") from fsys into a new executable file in dir,
+// and returns its path.
+func Extract(fsys fs.FS, target, dir string) (string, error) {
+	r, err := fsys.Open("intermediary/intermediary-" + target + ".gz")
+	if err != nil {
+		return "", err
+	}
+	defer r.Close() //nolint
+	gzr, err := gzip.NewReader(r)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+
+	// Exec'ing a file that any process holds open for writing fails with ETXTBSY. Our descriptor is close-on-exec, but
+	// a child forked concurrently by another goroutine holds a copy of it until that child execs. Holding ForkLock
+	// while the file is open for writing prevents any such fork, so the race cannot occur.
+	syscall.ForkLock.Lock()
+	defer syscall.ForkLock.Unlock()
+
+	w, err := os.CreateTemp(dir, fmt.Sprintf("%s%d-", extractPrefix, os.Getpid()))
+	if err != nil {
+		return "", err
+	}
+	defer w.Close() //nolint
+
+	_, err = io.Copy(w, gzr)
+	if err == nil {
+		err = w.Chmod(0700)
+	}
+	if err == nil {
+		err = w.Close()
+	}
+	if err != nil {
+		_ = os.Remove(w.Name())
+		return "", err
+	}
+	return w.Name(), nil
+}
@@ -0,0 +1,84 @@
+// Package exec is identical to os/exec except that it guarantees that subprocesses will terminate when their parent
+// does.
+//
+// It achieves this by embedding a tiny C binary that is launched as an intermediary, watches the parent PID for
+// termination, then terminates the child.
+package exec
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+type Error = exec.Error
+type ExitError = exec.ExitError
+
+// targetMap maps GOARCH-GOOS to the embedded intermediary target.
+var targetMap = map[string]string{
+	"arm64-linux":  "aarch64-linux",
+	"amd64-linux":  "x86_64-linux",
+	"arm64-darwin": "aarch64-macos",
+	"amd64-darwin": "x86_64-macos",
+	// Android runs the static Linux intermediaries unmodified.
+	"arm64-android": "aarch64-linux",
+	"amd64-android": "x86_64-linux",
+}
+
+var (
+	ErrDot       = exec.ErrDot
+	ErrNotFound  = exec.ErrNotFound
+	ErrWaitDelay = exec.ErrWaitDelay
+	// ErrUnsupported is returned when starting a command on a platform without an embedded intermediary.
+	ErrUnsupported = errors.New("exec: guaranteed subprocess termination is not supported on " + runtime.GOOS + "/" + runtime.GOARCH)
+	// ErrExtractFailed is returned by TryCommand and TryCommandContext when the intermediary cannot be extracted to an
+	// executable location.
+	ErrExtractFailed = errors.New("exec: could not extract an executable intermediary")
+	// ErrWatchdogDied is returned by CheckWatchdog when the intermediary was terminated unexpectedly.
+	ErrWatchdogDied = errors.New("exec: intermediary died unexpectedly")
+	// ErrWatchdogNotArmed is returned by StartRequiringWatchdog when the command's watchdogs could not be confirmed.
+	ErrWatchdogNotArmed = errors.New("exec: intermediary watchdog is not armed")
+)
+
+func LookPath(file string) (string, error) {
+	return exec.LookPath(file)
+}
+
+// SupportedTargets returns the GOOS/GOARCH pairs that have an embedded intermediary, eg. "linux/amd64".
+func SupportedTargets() []string {
+	targets := make([]string, 0, len(targetMap))
+	for key := range targetMap {
+		arch, goos, _ := strings.Cut(key, "-")
+		targets = append(targets, goos+"/"+arch)
+	}
+	sort.Strings(targets)
+	return targets
+}
+
+// SupportsCurrentPlatform reports whether commands created on this platform are guaranteed to terminate with their
+// parent. If it returns false, commands fail to start with ErrUnsupported, and callers may choose to fall back to
+// os/exec instead.
+func SupportsCurrentPlatform() bool {
+	return Supported
+}
+
+// StdFactory returns a function with the same signature as os/exec.Command that creates commands using this package.
+//
+// It can be injected into libraries that accept a command factory hook. The commands are still terminated if this
+// process dies, but as they are started through os/exec, ShutdownAll and NotifyAndForward do not see them.
+func StdFactory() func(name string, arg ...string) *exec.Cmd {
+	return func(name string, arg ...string) *exec.Cmd {
+		return Command(name, arg...).Unwrap()
+	}
+}
+
+// StdContextFactory returns a function with the same signature as os/exec.CommandContext that creates commands using
+// this package.
+func StdContextFactory() func(ctx context.Context, name string, arg ...string) *exec.Cmd {
+	return func(ctx context.Context, name string, arg ...string) *exec.Cmd {
+		return CommandContext(ctx, name, arg...).Unwrap()
+	}
+}
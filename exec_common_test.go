@@ -0,0 +1,30 @@
+package exec_test
+
+import (
+	"runtime"
+	"slices"
+	"testing"
+
+	"github.com/alecthomas/exec"
+)
+
+func TestSupportedTargets(t *testing.T) {
+	targets := exec.SupportedTargets()
+	if !slices.Contains(targets, "linux/amd64") || !slices.Contains(targets, "darwin/arm64") {
+		t.Errorf("Expected linux/amd64 and darwin/arm64 in %v", targets)
+	}
+	current := slices.Contains(targets, runtime.GOOS+"/"+runtime.GOARCH)
+	if current != exec.SupportsCurrentPlatform() {
+		t.Errorf("SupportsCurrentPlatform() = %v, but current platform in targets = %v", exec.SupportsCurrentPlatform(), current)
+	}
+}
+
+func TestUnsupportedPlatform(t *testing.T) {
+	if exec.Supported {
+		t.Skip("current platform is supported")
+	}
+	err := exec.Command("true").Run()
+	if err != exec.ErrUnsupported {
+		t.Errorf("Expected ErrUnsupported, got %v", err)
+	}
+}
@@ -3,11 +3,16 @@
 package exec_test
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"testing/fstest"
 	"time"
 
 	stdexec "os/exec"
@@ -287,3 +292,180 @@ func TestProcessGroupCleanup(t *testing.T) {
 		cleanupCmd.Run()
 	}
 }
+
+func TestNewFromStd(t *testing.T) {
+	std := stdexec.Command("echo", "hello", "world")
+	cmd := exec.NewFromStd(std)
+	if cmd.Unwrap() != std || std.Args[0] != "watchdog" {
+		t.Errorf("Expected command to run via the intermediary, got args %v", std.Args)
+	}
+	if len(cmd.Args) != 3 || cmd.Args[0] != "echo" {
+		t.Errorf("Expected logical args to be preserved, got %v", cmd.Args)
+	}
+
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("Command failed: %v", err)
+	}
+	expected := "hello world\n"
+	if string(output) != expected {
+		t.Errorf("Expected %q, got %q", expected, string(output))
+	}
+}
+
+func TestStdFactory(t *testing.T) {
+	var factory func(name string, arg ...string) *stdexec.Cmd = exec.StdFactory()
+	output, err := factory("echo", "factory").Output()
+	if err != nil {
+		t.Fatalf("Command failed: %v", err)
+	}
+	if string(output) != "factory\n" {
+		t.Errorf("Expected %q, got %q", "factory\n", string(output))
+	}
+}
+
+func TestExtract(t *testing.T) {
+	var compressed bytes.Buffer
+	gzw := gzip.NewWriter(&compressed)
+	if _, err := gzw.Write([]byte("#!/bin/sh\necho fake intermediary\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := gzw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	fsys := fstest.MapFS{"intermediary/intermediary-test-target.gz": {Data: compressed.Bytes()}}
+
+	dir := filepath.Join(t.TempDir(), "nested")
+	path, err := exec.Extract(fsys, "test-target", dir)
+	if err != nil {
+		t.Fatalf("Extract failed: %v", err)
+	}
+	if filepath.Dir(path) != dir {
+		t.Errorf("Expected %s to be extracted into %s", path, dir)
+	}
+	output, err := stdexec.Command(path).Output()
+	if err != nil {
+		t.Fatalf("Extracted file is not executable: %v", err)
+	}
+	if string(output) != "fake intermediary\n" {
+		t.Errorf("Expected %q, got %q", "fake intermediary\n", string(output))
+	}
+
+	if _, err := exec.Extract(fsys, "missing-target", dir); err == nil {
+		t.Error("Expected Extract to fail for a missing target")
+	}
+}
+
+const extractFailHelperEnv = "EXEC_TEST_EXTRACT_FAIL_HELPER"
+
+func TestTryCommand(t *testing.T) {
+	if os.Getenv(extractFailHelperEnv) != "" {
+		// Runs in a fresh process, before any intermediary has been extracted.
+		exec.SetIntermediarySource(fstest.MapFS{})
+		cmd, err := exec.TryCommand("echo")
+		if cmd != nil || !errors.Is(err, exec.ErrExtractFailed) {
+			fmt.Printf("expected ErrExtractFailed, got %v\n", err)
+			os.Exit(1)
+		}
+		std := stdexec.Command("echo", "fallback")
+		cmd, err = exec.TryNewFromStd(std)
+		if cmd != nil || !errors.Is(err, exec.ErrExtractFailed) {
+			fmt.Printf("expected ErrExtractFailed from TryNewFromStd, got %v\n", err)
+			os.Exit(1)
+		}
+		if output, err := std.Output(); err != nil || string(output) != "fallback\n" {
+			fmt.Printf("expected the os/exec command to be left runnable, got %q (%v)\n", output, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	cmd, err := exec.TryCommand("echo", "try")
+	if err != nil {
+		t.Fatalf("TryCommand failed: %v", err)
+	}
+	if output, err := cmd.Output(); err != nil || string(output) != "try\n" {
+		t.Errorf("Expected %q, got %q (%v)", "try\n", output, err)
+	}
+
+	helper := stdexec.Command(os.Args[0], "-test.run=^TestTryCommand$")
+	helper.Env = append(os.Environ(), extractFailHelperEnv+"=1")
+	if output, err := helper.CombinedOutput(); err != nil {
+		t.Errorf("Helper failed: %v\n%s", err, output)
+	}
+}
+
+func TestCleanup(t *testing.T) {
+	if err := exec.Command("true").Run(); err != nil {
+		t.Fatal(err)
+	}
+	path := exec.GetStats().IntermediaryPath
+	if err := exec.Cleanup(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) && !strings.Contains(path, "/go-exec/intermediary-") {
+		t.Errorf("Expected %s to be removed, got %v", path, err)
+	}
+	if exec.GetStats().Extracted {
+		t.Error("Expected the intermediary to no longer be extracted")
+	}
+	if output, err := exec.Command("echo", "again").Output(); err != nil || string(output) != "again\n" {
+		t.Errorf("Expected %q after re-extraction, got %q (%v)", "again\n", output, err)
+	}
+}
+
+const preinstalledHelperEnv = "EXEC_TEST_PREINSTALLED_HELPER"
+
+func TestSetIntermediaryPath(t *testing.T) {
+	if path := os.Getenv(preinstalledHelperEnv); path != "" {
+		// Runs in a fresh process, so that the source can be replaced.
+		exec.SetIntermediarySource(fstest.MapFS{})
+		exec.SetIntermediaryPath(path)
+		if _, err := exec.TryCommand("true"); !errors.Is(err, exec.ErrExtractFailed) {
+			fmt.Printf("expected a mismatched intermediary to be rejected with another source set, got %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	path := filepath.Join(t.TempDir(), "intermediary")
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0700)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := exec.WriteIntermediary(f); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	defer exec.Cleanup()               //nolint
+	defer exec.SetIntermediaryPath("") //nolint
+
+	exec.SetIntermediaryPath(path)
+	if err := exec.Cleanup(); err != nil {
+		t.Fatal(err)
+	}
+	if output, err := exec.Command("echo", "installed").Output(); err != nil || string(output) != "installed\n" {
+		t.Errorf("Expected %q, got %q (%v)", "installed\n", output, err)
+	}
+	if used := exec.GetStats().IntermediaryPath; used != path {
+		t.Errorf("Expected %s to be used, got %s", path, used)
+	}
+
+	if err := os.WriteFile(path, []byte("#!/bin/sh\nshift\nexec \"$@\"\n"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := exec.Cleanup(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := exec.TryCommand("true"); !errors.Is(err, exec.ErrExtractFailed) {
+		t.Errorf("Expected a mismatched intermediary to be rejected, got %v", err)
+	}
+
+	helper := stdexec.Command(os.Args[0], "-test.run=^TestSetIntermediaryPath$")
+	helper.Env = append(os.Environ(), preinstalledHelperEnv+"="+path)
+	if output, err := helper.CombinedOutput(); err != nil {
+		t.Errorf("Helper failed: %v\n%s", err, output)
+	}
+}
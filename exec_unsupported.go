@@ -0,0 +1,75 @@
+//go:build !((linux || darwin) && (amd64 || arm64))
+
+package exec
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// Supported is true if guaranteed subprocess termination is available on the target platform.
+const Supported = false
+
+// CommandContext returns a command that fails with ErrUnsupported when started, as this platform has no intermediary.
+//
+// This allows packages that depend on this one to build for all platforms.
+func CommandContext(ctx context.Context, name string, arg ...string) *Cmd {
+	std := exec.CommandContext(ctx, name, arg...)
+	std.Err = ErrUnsupported
+	return newCmd(std, std.Path, append([]string(nil), std.Args...))
+}
+
+// Command returns a command that fails with ErrUnsupported when started, as this platform has no intermediary.
+func Command(name string, arg ...string) *Cmd {
+	return CommandContext(context.Background(), name, arg...)
+}
+
+// TryCommandContext returns an error wrapping ErrExtractFailed and ErrUnsupported, as this platform has no
+// intermediary.
+func TryCommandContext(ctx context.Context, name string, arg ...string) (*Cmd, error) {
+	return nil, fmt.Errorf("%w: %w", ErrExtractFailed, ErrUnsupported)
+}
+
+// TryCommand returns an error wrapping ErrExtractFailed and ErrUnsupported, as this platform has no intermediary.
+func TryCommand(name string, arg ...string) (*Cmd, error) {
+	return TryCommandContext(context.Background(), name, arg...)
+}
+
+// NewFromStd marks a command created with os/exec as failing with ErrUnsupported, as this platform has no
+// intermediary.
+func NewFromStd(std *exec.Cmd) *Cmd {
+	if std.Err == nil {
+		std.Err = ErrUnsupported
+	}
+	return newCmd(std, std.Path, append([]string(nil), std.Args...))
+}
+
+// TryNewFromStd returns an error wrapping ErrExtractFailed and ErrUnsupported, as this platform has no intermediary.
+// The command is left unmodified.
+func TryNewFromStd(std *exec.Cmd) (*Cmd, error) {
+	return nil, fmt.Errorf("%w: %w", ErrExtractFailed, ErrUnsupported)
+}
+
+// syncArgs sets the path and arguments of the underlying command.
+func (c *Cmd) syncArgs() {
+	c.Cmd.Path = c.Path
+	c.Cmd.Args = c.Args
+}
+
+// Foreground has no effect on this platform.
+func Foreground(cmd *Cmd, tty *os.File) (restore func() error) {
+	return func() error { return nil }
+}
+
+func signalGroup(pid int, sig syscall.Signal) error {
+	return ErrUnsupported
+}
+
+func setPipeSize(f *os.File, size int) error {
+	return ErrUnsupported
+}
+
+func setpgid(cmd *Cmd) {}
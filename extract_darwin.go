@@ -0,0 +1,57 @@
+//go:build amd64 || arm64
+
+package exec
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os/exec"
+	"syscall"
+)
+
+// macOS has no anonymous executable files.
+const memfdSupported = false
+
+func extractMemfd(fsys fs.FS, target string) (string, error) {
+	return "", errors.ErrUnsupported
+}
+
+func closeMemfd() bool { return false }
+
+// signsIntermediary reports whether the intermediary is ad-hoc signed after extraction.
+func signsIntermediary() bool {
+	return adHocSign
+}
+
+// verifySignature returns an error if the code signature of the intermediary at path is missing or does not match its
+// contents.
+func verifySignature(path string) error {
+	if output, err := exec.Command("/usr/bin/codesign", "--verify", "--strict", path).CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: invalid signature: %w: %s", path, err, bytes.TrimSpace(output))
+	}
+	return nil
+}
+
+// prepareExtracted ad-hoc signs the extracted intermediary if requested, then checks that it can be launched.
+//
+// Gatekeeper and code signing enforcement do not fail exec. Instead the kernel kills the denied process with SIGKILL
+// as it launches, which would otherwise be indistinguishable from the intermediary dying later.
+func prepareExtracted(path string) error {
+	if adHocSign {
+		if output, err := exec.Command("/usr/bin/codesign", "--force", "--sign", "-", path).CombinedOutput(); err != nil {
+			return fmt.Errorf("%s: ad-hoc signing failed: %w: %s", path, err, bytes.TrimSpace(output))
+		}
+	}
+	probe := exec.Command(path, "/usr/bin/true")
+	probe.Args[0] = "watchdog"
+	err := probe.Run()
+	if status, ok := probe.ProcessState.Sys().(syscall.WaitStatus); ok && status.Signaled() && status.Signal() == syscall.SIGKILL {
+		return fmt.Errorf("%s: killed at launch (denied by Gatekeeper or code signing policy)", path)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: cannot execute: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+//go:build amd64 || arm64
+
+package exec
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	memfdSupported = true
+
+	mfdCloexec       = 0x1   // MFD_CLOEXEC
+	mfdAllowSealing  = 0x2   // MFD_ALLOW_SEALING
+	fAddSeals        = 0x409 // F_ADD_SEALS
+	fSealAll         = 0xf   // F_SEAL_SEAL | F_SEAL_SHRINK | F_SEAL_GROW | F_SEAL_WRITE
+	memfdName        = "go-exec-intermediary"
+	memfdPathPattern = "/proc/%d/fd/%d"
+)
+
+// memfd holds the in-memory intermediary open for the life of the process.
+var memfd *os.File
+
+// extractMemfd decompresses the intermediary for target from fsys into an anonymous, sealed, in-memory file, and
+// returns a path through which it can be executed.
+//
+// Nothing is written to disk, and the file disappears when this process exits. The path is under /proc/<pid> rather
+// than /proc/self, so that other processes started by this one, such as a shell, can also execute it. The
+// descriptor is close-on-exec, which does not prevent it being executed, as the path is resolved before exec closes
+// it.
+func extractMemfd(fsys fs.FS, target string) (string, error) {
+	r, err := fsys.Open("intermediary/intermediary-" + target + ".gz")
+	if err != nil {
+		return "", err
+	}
+	defer r.Close() //nolint
+	gzr, err := gzip.NewReader(r)
+	if err != nil {
+		return "", err
+	}
+	name, err := syscall.BytePtrFromString(memfdName)
+	if err != nil {
+		return "", err
+	}
+	fd, _, errno := syscall.Syscall(sysMemfdCreate, uintptr(unsafe.Pointer(name)), mfdCloexec|mfdAllowSealing, 0)
+	if errno != 0 {
+		return "", fmt.Errorf("memfd_create: %w", errno)
+	}
+	f := os.NewFile(fd, memfdName)
+	if _, err := io.Copy(f, gzr); err != nil {
+		_ = f.Close()
+		return "", err
+	}
+	// Sealing makes the contents immutable, so the intermediary cannot be modified after it has been checked.
+	if _, _, errno := syscall.Syscall(syscall.SYS_FCNTL, fd, fAddSeals, fSealAll); errno != 0 {
+		_ = f.Close()
+		return "", fmt.Errorf("sealing memfd: %w", errno)
+	}
+	path := fmt.Sprintf(memfdPathPattern, os.Getpid(), fd)
+	if err := syscall.Access(path, accessExecute); err != nil {
+		_ = f.Close()
+		return "", fmt.Errorf("%s: cannot execute: %w", path, err)
+	}
+	memfd = f
+	return path, nil
+}
+
+// closeMemfd closes the in-memory intermediary, if it is in use, and reports whether it was.
+func closeMemfd() bool {
+	if memfd == nil {
+		return false
+	}
+	_ = memfd.Close()
+	memfd = nil
+	return true
+}
+
+// signsIntermediary reports false, as SetAdHocSign has no effect on Linux.
+func signsIntermediary() bool {
+	return false
+}
+
+// verifySignature is never called on Linux, where the intermediary is not signed.
+func verifySignature(path string) error {
+	return errors.ErrUnsupported
+}
+
+// prepareExtracted has nothing to do on Linux, where denials are detected by access(2).
+func prepareExtracted(path string) error {
+	return nil
+}
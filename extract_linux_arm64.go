@@ -0,0 +1,3 @@
+package exec
+
+const sysMemfdCreate = 279 // SYS_MEMFD_CREATE
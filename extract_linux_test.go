@@ -0,0 +1,19 @@
+//go:build amd64 || arm64
+
+package exec_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/exec"
+)
+
+func TestExtractMemfd(t *testing.T) {
+	if output, err := exec.Command("echo", "memfd").Output(); err != nil || string(output) != "memfd\n" {
+		t.Fatalf("Expected %q, got %q (%v)", "memfd\n", output, err)
+	}
+	if path := exec.GetStats().IntermediaryPath; !strings.HasPrefix(path, "/proc/") {
+		t.Errorf("Expected the intermediary to be held in memory, got %q", path)
+	}
+}
@@ -0,0 +1,84 @@
+package exec
+
+import (
+	"bytes"
+	"errors"
+	"io"
+)
+
+// FilterOutput passes each line of the command's stdout and stderr through filter before it is written to Stdout or
+// Stderr. It must be called after they are set and before Start, and nil Stdout or Stderr are left unchanged.
+//
+// filter is called with each line including its trailing newline, and with a final line that has none. It returns the
+// replacement, which may be empty to drop the line, eg. to remove a startup banner or normalise paths. Lines longer
+// than 64KiB are passed to filter in parts.
+func FilterOutput(cmd *Cmd, filter func(line []byte) []byte) {
+	same := cmd.Stdout == cmd.Stderr
+	if cmd.Stdout != nil {
+		cmd.Stdout = &filterWriter{w: cmd.Stdout, filter: filter}
+	}
+	if same {
+		cmd.Stderr = cmd.Stdout
+	} else if cmd.Stderr != nil {
+		cmd.Stderr = &filterWriter{w: cmd.Stderr, filter: filter}
+	}
+}
+
+// filterWriter applies a filter to each line written to it.
+//
+// It implements io.ReaderFrom, which os/exec uses to copy from the child, so that it can detect EOF and filter a final
+// line without a trailing newline.
+type filterWriter struct {
+	w       io.Writer
+	filter  func(line []byte) []byte
+	pending []byte
+}
+
+func (f *filterWriter) Write(p []byte) (int, error) {
+	f.pending = append(f.pending, p...)
+	for {
+		i := bytes.IndexByte(f.pending, '\n')
+		if i < 0 && len(f.pending) < maxLineLength {
+			break
+		}
+		if i < 0 {
+			i = maxLineLength - 1
+		}
+		if err := f.emit(f.pending[:i+1]); err != nil {
+			return 0, err
+		}
+		f.pending = f.pending[i+1:]
+	}
+	return len(p), nil
+}
+
+func (f *filterWriter) ReadFrom(r io.Reader) (int64, error) {
+	var total int64
+	buf := make([]byte, streamChunkSize)
+	for {
+		n, err := r.Read(buf)
+		total += int64(n)
+		if _, werr := f.Write(buf[:n]); werr != nil {
+			return total, werr
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return total, err
+		}
+	}
+	if len(f.pending) == 0 {
+		return total, nil
+	}
+	err := f.emit(f.pending)
+	f.pending = nil
+	return total, err
+}
+
+func (f *filterWriter) emit(line []byte) error {
+	if out := f.filter(line); len(out) > 0 {
+		_, err := f.w.Write(out)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+//go:build (linux || darwin) && (amd64 || arm64)
+
+package exec_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/alecthomas/exec"
+)
+
+func TestFilterOutput(t *testing.T) {
+	var stdout bytes.Buffer
+	cmd := exec.Command("sh", "-c", `echo "Tool v1.0 (c) Example Corp"; echo "/home/user/src/a.go:1: error"; printf "/home/user/src/b.go"`)
+	cmd.Stdout = &stdout
+	exec.FilterOutput(cmd, func(line []byte) []byte {
+		if bytes.HasPrefix(line, []byte("Tool v")) {
+			return nil
+		}
+		return bytes.ReplaceAll(line, []byte("/home/user/"), []byte("$HOME/"))
+	})
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "$HOME/src/a.go:1: error\n$HOME/src/b.go"; stdout.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, stdout.String())
+	}
+}
@@ -0,0 +1,56 @@
+package exec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// FirstLine starts cmd and returns the first line it writes to stdout, such as a version banner, a port number or a
+// token, without the line terminator.
+//
+// The command is left running, and all of its output, including the first line, continues to be written to Stdout.
+// FirstLine waits for the command in the background, so the caller must call the returned wait function rather than
+// cmd.Wait to wait for it and obtain its result. If the command exits without completing a line, the partial line is
+// returned, or an error wrapping io.ErrUnexpectedEOF if it wrote nothing. If ctx is done first, FirstLine returns an
+// error but does not stop the command.
+func FirstLine(ctx context.Context, cmd *Cmd) (line string, wait func() error, err error) {
+	lines := make(chan string, 1)
+	var once sync.Once
+	lw := newLineWriter(cmd.Stdout, func(line []byte) {
+		once.Do(func() { lines <- string(line) })
+	})
+	cmd.Stdout = lw
+	if err := cmd.Start(); err != nil {
+		return "", nil, err
+	}
+	exited := make(chan struct{})
+	var waitErr error
+	go func() {
+		waitErr = cmd.Wait()
+		close(exited)
+	}()
+	wait = func() error {
+		<-exited
+		return waitErr
+	}
+	select {
+	case line := <-lines:
+		return line, wait, nil
+	case <-exited:
+		// Output is fully copied once Wait returns, so a line written just before exiting is already available.
+		select {
+		case line := <-lines:
+			return line, wait, nil
+		default:
+		}
+		if len(lw.pending) > 0 {
+			return string(lw.pending), wait, nil
+		}
+		return "", wait, fmt.Errorf("command exited without writing a line: %w", errors.Join(io.ErrUnexpectedEOF, waitErr))
+	case <-ctx.Done():
+		return "", wait, fmt.Errorf("waiting for first line: %w", context.Cause(ctx))
+	}
+}
@@ -0,0 +1,65 @@
+//go:build (linux || darwin) && (amd64 || arm64)
+
+package exec_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/exec"
+)
+
+func TestFirstLine(t *testing.T) {
+	var stdout bytes.Buffer
+	cmd := exec.Command("sh", "-c", "echo 'port 1234'; sleep 0.2; echo done")
+	cmd.Stdout = &stdout
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	line, wait, err := exec.FirstLine(ctx, cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line != "port 1234" {
+		t.Errorf("Expected %q, got %q", "port 1234", line)
+	}
+	if err := wait(); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "port 1234\ndone\n"; stdout.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, stdout.String())
+	}
+}
+
+func TestFirstLineExitWithoutLine(t *testing.T) {
+	line, wait, err := exec.FirstLine(context.Background(), exec.Command("printf", "foo"))
+	if err != nil || line != "foo" {
+		t.Errorf("Expected partial line %q, got %q (%v)", "foo", line, err)
+	}
+	if err := wait(); err != nil {
+		t.Error(err)
+	}
+
+	_, wait, err = exec.FirstLine(context.Background(), exec.Command("true"))
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Expected unexpected EOF, got %v", err)
+	}
+	if err := wait(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFirstLineTimeout(t *testing.T) {
+	cmd := exec.Command("sleep", "10")
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, wait, err := exec.FirstLine(ctx, cmd)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded, got %v", err)
+	}
+	_ = cmd.Process.Kill()
+	_ = wait()
+}
@@ -0,0 +1,35 @@
+//go:build (linux || darwin) && (amd64 || arm64)
+
+package exec
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+	"unsafe"
+)
+
+// Foreground configures cmd to run as the foreground job of the terminal tty, as a shell would, so that terminal
+// generated signals such as Ctrl-C and Ctrl-Z are delivered to the command rather than to this process.
+//
+// cmd must not have been started. Call the returned function after the command exits to return the terminal to this
+// process's process group.
+func Foreground(cmd *Cmd, tty *os.File) (restore func() error) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+	cmd.SysProcAttr.Foreground = true
+	cmd.SysProcAttr.Ctty = int(tty.Fd())
+	return func() error {
+		// A background process group changing the foreground group is sent SIGTTOU, which would stop us.
+		signal.Ignore(syscall.SIGTTOU)
+		defer signal.Reset(syscall.SIGTTOU)
+		pgrp := int32(syscall.Getpgrp())
+		_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, tty.Fd(), uintptr(syscall.TIOCSPGRP), uintptr(unsafe.Pointer(&pgrp)))
+		if errno != 0 {
+			return os.NewSyscallError("tcsetpgrp", errno)
+		}
+		return nil
+	}
+}
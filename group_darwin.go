@@ -0,0 +1,37 @@
+//go:build amd64 || arm64
+
+package exec
+
+import (
+	"bytes"
+	"os/exec"
+	"strconv"
+)
+
+// groupParents returns the parent of each live process in the process group pgid, by pid.
+//
+// macOS has no /proc, so this lists processes with ps.
+func groupParents(pgid int) (map[int]int, error) {
+	out, err := exec.Command("/bin/ps", "-A", "-o", "pid=,ppid=,pgid=,stat=").Output()
+	if err != nil {
+		return nil, err
+	}
+	parents := map[int]int{}
+	for line := range bytes.Lines(out) {
+		fields := bytes.Fields(line)
+		if len(fields) < 4 || fields[3][0] == 'Z' {
+			continue
+		}
+		if group, err := strconv.Atoi(string(fields[2])); err != nil || group != pgid {
+			continue
+		}
+		pid, err := strconv.Atoi(string(fields[0]))
+		if err != nil {
+			continue
+		}
+		if parent, err := strconv.Atoi(string(fields[1])); err == nil {
+			parents[pid] = parent
+		}
+	}
+	return parents, nil
+}
@@ -0,0 +1,40 @@
+//go:build amd64 || arm64
+
+package exec
+
+import (
+	"bytes"
+	"os"
+	"strconv"
+)
+
+// groupParents returns the parent of each live process in the process group pgid, by pid.
+func groupParents(pgid int) (map[int]int, error) {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil, err
+	}
+	parents := map[int]int{}
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		stat, err := os.ReadFile("/proc/" + entry.Name() + "/stat")
+		if err != nil {
+			continue // Exited since the directory was read.
+		}
+		// The state, ppid and pgrp follow the parenthesised command name, which may itself contain parentheses.
+		fields := bytes.Fields(stat[bytes.LastIndexByte(stat, ')')+1:])
+		if len(fields) < 3 || string(fields[0]) == "Z" {
+			continue
+		}
+		if group, err := strconv.Atoi(string(fields[2])); err != nil || group != pgid {
+			continue
+		}
+		if parent, err := strconv.Atoi(string(fields[1])); err == nil {
+			parents[pid] = parent
+		}
+	}
+	return parents, nil
+}
@@ -0,0 +1,52 @@
+package exec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"syscall"
+)
+
+// Guard reports whether the change made by a command has already been applied, in which case it is not run again.
+type Guard func(ctx context.Context) (done bool, err error)
+
+// GuardCommand returns a Guard that runs cmd, and reports the change as applied if it exits successfully, eg.
+// "test -f /etc/app.conf". A non-zero exit means not applied, and a failure to start cmd is an error. If the Guard's
+// context is done before cmd exits, cmd's process group is killed and the context's error is returned. The Guard can
+// only be used once.
+func GuardCommand(cmd *Cmd) Guard {
+	return func(ctx context.Context) (bool, error) {
+		if err := cmd.Start(); err != nil {
+			return false, err
+		}
+		stop := context.AfterFunc(ctx, func() {
+			markSignalled(cmd)
+			_ = signalGroup(cmd.Process.Pid, syscall.SIGKILL)
+		})
+		err := cmd.Wait()
+		if !stop() && ctx.Err() != nil {
+			return false, context.Cause(ctx)
+		}
+		var exitErr *ExitError
+		switch {
+		case err == nil:
+			return true, nil
+		case errors.As(err, &exitErr):
+			return false, nil
+		default:
+			return false, err
+		}
+	}
+}
+
+// RunGuarded runs cmd unless guard reports that it has already been applied, in which case skipped is true.
+func RunGuarded(ctx context.Context, cmd *Cmd, guard Guard) (skipped bool, err error) {
+	done, err := guard(ctx)
+	if err != nil {
+		return false, fmt.Errorf("guard: %w", err)
+	}
+	if done {
+		return true, nil
+	}
+	return false, cmd.Run()
+}
@@ -0,0 +1,53 @@
+//go:build (linux || darwin) && (amd64 || arm64)
+
+package exec_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/exec"
+)
+
+func TestRunGuarded(t *testing.T) {
+	ctx := context.Background()
+	file := filepath.Join(t.TempDir(), "applied")
+	apply := func() (bool, error) {
+		return exec.RunGuarded(ctx, exec.Command("touch", file), exec.GuardCommand(exec.Command("test", "-f", file)))
+	}
+	skipped, err := apply()
+	if err != nil || skipped {
+		t.Fatalf("Expected first run to apply, got skipped=%v err=%v", skipped, err)
+	}
+	if _, err := os.Stat(file); err != nil {
+		t.Fatal(err)
+	}
+	skipped, err = apply()
+	if err != nil || !skipped {
+		t.Errorf("Expected second run to be skipped, got skipped=%v err=%v", skipped, err)
+	}
+
+	guard := exec.Command("true")
+	guard.Dir = filepath.Join(t.TempDir(), "missing")
+	_, err = exec.RunGuarded(ctx, exec.Command("true"), exec.GuardCommand(guard))
+	if err == nil {
+		t.Error("Expected a guard that cannot run to fail")
+	}
+}
+
+func TestGuardCommandCancelled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := exec.GuardCommand(exec.Command("sleep", "10"))(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected %v, got %v", context.DeadlineExceeded, err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected the guard to be killed on cancellation, took %s", elapsed)
+	}
+}
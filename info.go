@@ -0,0 +1,59 @@
+//go:build (linux || darwin) && (amd64 || arm64)
+
+package exec
+
+import (
+	"runtime"
+	"time"
+)
+
+// Mechanism is the method used by the intermediary to detect that its parent has died.
+type Mechanism string
+
+const (
+	// MechanismPoll periodically checks whether the parent is still alive.
+	MechanismPoll Mechanism = "poll"
+	// MechanismPdeathsig has the kernel signal the command directly when its parent dies. See StrategyPdeathsig.
+	MechanismPdeathsig Mechanism = "pdeathsig"
+)
+
+// These must match the constants compiled into intermediary/intermediary.c.
+const (
+	intermediaryPollInterval = 50 * time.Millisecond
+	intermediaryKillGrace    = 100 * time.Millisecond
+)
+
+// IntermediaryInfo describes the guarantee provided by the intermediary on the current platform.
+type IntermediaryInfo struct {
+	// Target is the platform the embedded intermediary was built for, eg. "x86_64-linux".
+	Target string
+	// Mechanism used to detect parent death.
+	Mechanism Mechanism
+	// PollInterval is how often the parent is checked when Mechanism is MechanismPoll.
+	PollInterval time.Duration
+	// KillGrace is how long the child's process group is given to exit after SIGTERM before it is sent SIGKILL.
+	KillGrace time.Duration
+	// MaxLatency is the worst case delay between the parent dying and the child's process group being sent SIGKILL.
+	MaxLatency time.Duration
+	// Emulator running this process and the intermediary, such as qemu-user or EmulatorRosetta, or "" if they run
+	// natively. Emulation increases latency, and slows commands built for the same architecture.
+	Emulator string
+}
+
+// Intermediary reports the mechanism and detection latency of the intermediary for the current platform.
+//
+// The poll interval is currently fixed at build time of the intermediary. If StrategyPdeathsig is in use, no
+// intermediary is run, and the command is killed without delay.
+func Intermediary() IntermediaryInfo {
+	if strategy == StrategyPdeathsig {
+		return IntermediaryInfo{Target: targetMap[runtime.GOARCH+"-"+runtime.GOOS], Mechanism: MechanismPdeathsig, Emulator: selfEmulator()}
+	}
+	return IntermediaryInfo{
+		Target:       targetMap[runtime.GOARCH+"-"+runtime.GOOS],
+		Mechanism:    MechanismPoll,
+		PollInterval: intermediaryPollInterval,
+		KillGrace:    intermediaryKillGrace,
+		MaxLatency:   intermediaryPollInterval + intermediaryKillGrace,
+		Emulator:     selfEmulator(),
+	}
+}
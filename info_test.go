@@ -0,0 +1,34 @@
+//go:build (linux || darwin) && (amd64 || arm64)
+
+package exec_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/alecthomas/exec"
+)
+
+func TestIntermediary(t *testing.T) {
+	info := exec.Intermediary()
+	if info.Target == "" {
+		t.Error("Expected a target for a supported platform")
+	}
+	if info.Mechanism != exec.MechanismPoll {
+		t.Errorf("Expected mechanism %q, got %q", exec.MechanismPoll, info.Mechanism)
+	}
+	if info.MaxLatency < info.PollInterval {
+		t.Errorf("Expected max latency %s to include poll interval %s", info.MaxLatency, info.PollInterval)
+	}
+}
+
+func TestEmbeddedSize(t *testing.T) {
+	info, err := os.Stat("intermediary/intermediary-" + exec.Intermediary().Target + ".gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Only the blob for the target platform should be embedded.
+	if size := exec.EmbeddedSize(); size != info.Size() {
+		t.Errorf("Expected embedded size %d, got %d", info.Size(), size)
+	}
+}
@@ -0,0 +1,112 @@
+package exec
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// IsolateHome gives the command a new, empty home directory, and XDG config, cache, data and state directories within
+// it, so that tools such as git, gh and npm neither read nor modify the user's configuration. Call the returned
+// function after the command exits to remove the directory.
+func IsolateHome(cmd *Cmd) (cleanup func() error, err error) {
+	home, err := os.MkdirTemp("", "go-exec-home-")
+	if err != nil {
+		return nil, err
+	}
+	cleanup = func() error { return os.RemoveAll(home) }
+	vars := []string{"HOME=" + home}
+	for _, dir := range []struct{ env, path string }{
+		{"XDG_CONFIG_HOME", ".config"},
+		{"XDG_CACHE_HOME", ".cache"},
+		{"XDG_DATA_HOME", ".local/share"},
+		{"XDG_STATE_HOME", ".local/state"},
+	} {
+		path := filepath.Join(home, dir.path)
+		if err := os.MkdirAll(path, 0700); err != nil {
+			_ = cleanup()
+			return nil, err
+		}
+		vars = append(vars, dir.env+"="+path)
+	}
+	setEnv(cmd, vars...)
+	return cleanup, nil
+}
+
+// setEnv sets the given NAME=value pairs in the command's environment, replacing any existing values.
+func setEnv(cmd *Cmd, vars ...string) {
+	names := map[string]bool{}
+	for _, kv := range vars {
+		name, _, _ := strings.Cut(kv, "=")
+		names[name] = true
+	}
+	unsetEnv(cmd, func(name string) bool { return names[name] })
+	cmd.Env = append(cmd.Env, vars...)
+}
+
+// unsetEnv removes variables whose name matches from the command's environment.
+func unsetEnv(cmd *Cmd, match func(name string) bool) {
+	env := cmd.Env
+	if env == nil {
+		env = os.Environ()
+	}
+	out := make([]string, 0, len(env))
+	for _, kv := range env {
+		if name, _, _ := strings.Cut(kv, "="); !match(name) {
+			out = append(out, kv)
+		}
+	}
+	cmd.Env = out
+}
+
+// hermeticNames, hermeticPrefixes and hermeticSuffixes identify environment variables that carry credentials or point
+// at them.
+var (
+	hermeticNames = []string{
+		"SSH_AUTH_SOCK", "SSH_AGENT_PID", "SSH_ASKPASS", "GIT_ASKPASS", "GIT_SSH", "GIT_SSH_COMMAND",
+		"GOOGLE_APPLICATION_CREDENTIALS", "KUBECONFIG", "DOCKER_CONFIG", "NETRC", "GNUPGHOME", "GPG_AGENT_INFO",
+	}
+	hermeticPrefixes = []string{"GIT_CONFIG", "AWS_", "AZURE_", "ARM_", "CLOUDSDK_", "GCLOUD_", "OS_", "VAULT_"}
+	hermeticSuffixes = []string{"_TOKEN", "_SECRET", "_PASSWORD", "_API_KEY", "_ACCESS_KEY", "_CREDENTIALS"}
+)
+
+// Hermetic isolates the command's home directory as IsolateHome does, and additionally removes environment variables
+// that give access to credentials, so that untrusted code such as repository hooks cannot use them. Variables named
+// in allow are kept. Call the returned function after the command exits to remove the home directory.
+//
+// Removed variables include SSH and GPG agent sockets, git configuration overrides and askpass helpers, cloud
+// provider variables such as AWS_*, and any variable whose name ends in _TOKEN, _SECRET, _PASSWORD, _API_KEY,
+// _ACCESS_KEY or _CREDENTIALS. System-wide git configuration is disabled, in addition to the global configuration
+// hidden by the new home directory.
+func Hermetic(cmd *Cmd, allow ...string) (cleanup func() error, err error) {
+	allowed := map[string]bool{}
+	for _, name := range allow {
+		allowed[name] = true
+	}
+	unsetEnv(cmd, func(name string) bool {
+		if allowed[name] {
+			return false
+		}
+		if slices.Contains(hermeticNames, name) {
+			return true
+		}
+		for _, prefix := range hermeticPrefixes {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		}
+		for _, suffix := range hermeticSuffixes {
+			if strings.HasSuffix(name, suffix) {
+				return true
+			}
+		}
+		return false
+	})
+	cleanup, err = IsolateHome(cmd)
+	if err != nil {
+		return nil, err
+	}
+	setEnv(cmd, "GIT_CONFIG_NOSYSTEM=1")
+	return cleanup, nil
+}
@@ -0,0 +1,80 @@
+//go:build (linux || darwin) && (amd64 || arm64)
+
+package exec_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/exec"
+)
+
+func TestIsolateHome(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", "/real/config")
+	cmd := exec.Command("sh", "-c", `touch "$HOME/.gitconfig"; echo "$HOME $XDG_CONFIG_HOME"`)
+	cleanup, err := exec.IsolateHome(cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := strings.Fields(string(output))
+	if len(fields) != 2 {
+		t.Fatalf("Unexpected output %q", output)
+	}
+	home, config := fields[0], fields[1]
+	if realHome, _ := os.UserHomeDir(); home == realHome || config != filepath.Join(home, ".config") {
+		t.Errorf("Expected isolated home and config, got %q and %q", home, config)
+	}
+	if _, err := os.Stat(filepath.Join(home, ".gitconfig")); err != nil {
+		t.Errorf("Expected file in isolated home: %v", err)
+	}
+	count := 0
+	for _, kv := range cmd.Env {
+		if strings.HasPrefix(kv, "XDG_CONFIG_HOME=") {
+			count++
+		}
+	}
+	if count != 1 {
+		t.Errorf("Expected XDG_CONFIG_HOME to be replaced, found %d entries", count)
+	}
+	if err := cleanup(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(home); !os.IsNotExist(err) {
+		t.Error("Expected isolated home to be removed")
+	}
+}
+
+func TestHermetic(t *testing.T) {
+	t.Setenv("SSH_AUTH_SOCK", "/tmp/agent.sock")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("GITHUB_TOKEN", "token")
+	t.Setenv("NPM_TOKEN", "allowed")
+	t.Setenv("KEEP_ME", "kept")
+	cmd := exec.Command("env")
+	cleanup, err := exec.Hermetic(cmd, "NPM_TOKEN")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup() //nolint
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := string(output)
+	for _, removed := range []string{"SSH_AUTH_SOCK=", "AWS_SECRET_ACCESS_KEY=", "GITHUB_TOKEN="} {
+		if strings.Contains(env, removed) {
+			t.Errorf("Expected %s to be removed", removed)
+		}
+	}
+	for _, kept := range []string{"NPM_TOKEN=allowed\n", "KEEP_ME=kept\n", "GIT_CONFIG_NOSYSTEM=1\n"} {
+		if !strings.Contains(env, kept) {
+			t.Errorf("Expected %q in environment", kept)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+//go:build (linux || darwin) && (amd64 || arm64)
+
+package exec
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// extractPrefix is the file name prefix of extracted intermediaries, which are named <prefix><pid>-<random>.
+const extractPrefix = "go-exec-intermediary-"
+
+// staleAge is how old an extracted intermediary must be before it is considered for removal, to avoid racing with a
+// process that is still extracting it.
+const staleAge = time.Minute
+
+// CleanStale removes intermediaries left behind in the extraction directories by processes that have since exited,
+// and returns the paths removed.
+//
+// This is done automatically when the intermediary is first extracted, so it is only needed to clean up on demand.
+func CleanStale() ([]string, error) {
+	var removed []string
+	var errs []error
+	for _, dir := range extractDirs() {
+		paths, err := cleanStaleIn(dir)
+		removed = append(removed, paths...)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+	return removed, errors.Join(errs...)
+}
+
+func cleanStaleIn(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var removed []string
+	var errs []error
+	for _, entry := range entries {
+		rest, ok := strings.CutPrefix(entry.Name(), extractPrefix)
+		if !ok || !entry.Type().IsRegular() {
+			continue
+		}
+		pidStr, _, ok := strings.Cut(rest, "-")
+		if !ok {
+			continue
+		}
+		pid, err := strconv.Atoi(pidStr)
+		if err != nil || pid == os.Getpid() || !processExited(pid) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || time.Since(info.ModTime()) < staleAge {
+			continue
+		}
+		if stat, ok := info.Sys().(*syscall.Stat_t); !ok || int(stat.Uid) != os.Getuid() {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+			continue
+		}
+		removed = append(removed, path)
+	}
+	return removed, errors.Join(errs...)
+}
+
+// processExited reports whether no process with the given pid exists.
+func processExited(pid int) bool {
+	return errors.Is(syscall.Kill(pid, 0), syscall.ESRCH)
+}
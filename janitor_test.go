@@ -0,0 +1,55 @@
+//go:build (linux || darwin) && (amd64 || arm64)
+
+package exec_test
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	stdexec "os/exec"
+
+	"github.com/alecthomas/exec"
+)
+
+func TestCleanStale(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("TMPDIR", dir)
+
+	// A process that has exited, so its pid is dead.
+	dead := stdexec.Command("true")
+	if err := dead.Run(); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-time.Hour)
+	create := func(name string, mtime time.Time) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, nil, 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	stale := create("go-exec-intermediary-"+strconv.Itoa(dead.Process.Pid)+"-123", old)
+	recent := create("go-exec-intermediary-"+strconv.Itoa(dead.Process.Pid)+"-456", time.Now())
+	live := create("go-exec-intermediary-"+strconv.Itoa(os.Getpid())+"-789", old)
+	unrelated := create("unrelated", old)
+
+	removed, err := exec.CleanStale()
+	if err != nil {
+		t.Fatalf("CleanStale failed: %v", err)
+	}
+	if !slices.Contains(removed, stale) {
+		t.Errorf("Expected %s to be removed, removed %v", stale, removed)
+	}
+	for _, path := range []string{recent, live, unrelated} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("Expected %s to be kept: %v", path, err)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package exec
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// maxReportOutput bounds the amount of stderr included for each command in a JUnit report.
+const maxReportOutput = 4096
+
+// Report collects the results of commands, so that they can be published as a JUnit XML report by CI systems. The
+// zero value is ready to use, and it is safe for concurrent use.
+type Report struct {
+	lock    sync.Mutex
+	results []*Result
+}
+
+// Run cmd with RunResult and add the result to the report.
+func (r *Report) Run(cmd *Cmd) *Result {
+	result := RunResult(cmd)
+	r.Add(result)
+	return result
+}
+
+// Add a result to the report.
+func (r *Report) Add(result *Result) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.results = append(r.results, result)
+}
+
+type junitSuite struct {
+	XMLName   xml.Name    `xml:"testsuite"`
+	Name      string      `xml:"name,attr"`
+	Tests     int         `xml:"tests,attr"`
+	Failures  int         `xml:"failures,attr"`
+	Time      string      `xml:"time,attr"`
+	Timestamp string      `xml:"timestamp,attr,omitempty"`
+	Cases     []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemErr string        `xml:"system-err,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes the report to w as a JUnit XML test suite named suite, with a test case for each command.
+//
+// Failed commands are reported as failures, with the lines returned by Result.ErrorLines. The end of each command's
+// stderr is included as its system-err.
+func (r *Report) WriteJUnit(w io.Writer, suite string) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	out := junitSuite{Name: suite, Tests: len(r.results)}
+	var total time.Duration
+	for _, result := range r.results {
+		total += result.Duration
+		tc := junitCase{Name: result.Command, ClassName: suite, Time: junitSeconds(result.Duration)}
+		if result.Err != nil {
+			out.Failures++
+			tc.Failure = &junitFailure{Message: result.status()}
+			for _, line := range result.ErrorLines() {
+				tc.Failure.Text += line + "\n"
+			}
+		}
+		stderr := result.Stderr
+		if len(stderr) > maxReportOutput {
+			stderr = stderr[len(stderr)-maxReportOutput:]
+		}
+		tc.SystemErr = string(stderr)
+		out.Cases = append(out.Cases, tc)
+	}
+	out.Time = junitSeconds(total)
+	if len(r.results) > 0 {
+		out.Timestamp = r.results[0].Started.UTC().Format("2006-01-02T15:04:05")
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(out); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func junitSeconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
@@ -0,0 +1,50 @@
+//go:build (linux || darwin) && (amd64 || arm64)
+
+package exec_test
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+
+	"github.com/alecthomas/exec"
+)
+
+func TestReportWriteJUnit(t *testing.T) {
+	var report exec.Report
+	report.Run(exec.Command("true"))
+	report.Run(exec.Command("sh", "-c", "echo 'fatal: not a git repository' >&2; exit 128"))
+
+	var buf bytes.Buffer
+	if err := report.WriteJUnit(&buf, "setup"); err != nil {
+		t.Fatal(err)
+	}
+	var suite struct {
+		Tests    int `xml:"tests,attr"`
+		Failures int `xml:"failures,attr"`
+		Cases    []struct {
+			Name    string `xml:"name,attr"`
+			Failure *struct {
+				Message string `xml:"message,attr"`
+				Text    string `xml:",chardata"`
+			} `xml:"failure"`
+		} `xml:"testcase"`
+	}
+	if err := xml.Unmarshal(buf.Bytes(), &suite); err != nil {
+		t.Fatalf("Invalid XML: %v\n%s", err, buf.String())
+	}
+	if suite.Tests != 2 || suite.Failures != 1 || len(suite.Cases) != 2 {
+		t.Fatalf("Unexpected report:\n%s", buf.String())
+	}
+	truePath, err := exec.LookPath("true")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if suite.Cases[0].Name != truePath || suite.Cases[0].Failure != nil {
+		t.Errorf("Expected passing test case for true, got %+v", suite.Cases[0])
+	}
+	failure := suite.Cases[1].Failure
+	if failure == nil || failure.Message != "failed with exit code 128" || failure.Text != "fatal: not a git repository\n" {
+		t.Errorf("Unexpected failure %+v", failure)
+	}
+}
@@ -0,0 +1,53 @@
+//go:build (linux || darwin) && (amd64 || arm64)
+
+package exec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+)
+
+// ErrLocked is returned by RunLocked when the lock is held by another process and it was asked not to wait.
+var ErrLocked = errors.New("exec: lock is held by another process")
+
+// lockPollInterval is how often RunLocked retries a held lock.
+const lockPollInterval = 50 * time.Millisecond
+
+// RunLocked runs cmd while holding an exclusive advisory lock (flock(2)) on lockPath, which is created if necessary,
+// to prevent concurrent runs of tools that are not reentrant, such as package managers, across processes.
+//
+// If the lock is held and wait is false, RunLocked returns ErrLocked immediately. Otherwise it waits until the lock
+// is released or ctx is done, so a timeout can be set with a context deadline. The lock is released when cmd exits, or
+// by the operating system if this process dies.
+func RunLocked(ctx context.Context, cmd *Cmd, lockPath string, wait bool) error {
+	f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close() //nolint
+	ticker := time.NewTicker(lockPollInterval)
+	defer ticker.Stop()
+	for {
+		err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, syscall.EWOULDBLOCK) {
+			return fmt.Errorf("lock %s: %w", lockPath, err)
+		}
+		if !wait {
+			return fmt.Errorf("%s: %w", lockPath, ErrLocked)
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for lock %s: %w", lockPath, context.Cause(ctx))
+		case <-ticker.C:
+		}
+	}
+	defer syscall.Flock(int(f.Fd()), syscall.LOCK_UN) //nolint
+	return cmd.Run()
+}
@@ -0,0 +1,48 @@
+//go:build (linux || darwin) && (amd64 || arm64)
+
+package exec_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/exec"
+)
+
+func TestRunLocked(t *testing.T) {
+	lockPath := filepath.Join(t.TempDir(), "lock")
+	ctx := context.Background()
+	started := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		cmd := exec.Command("sh", "-c", "sleep 0.5")
+		close(started)
+		done <- exec.RunLocked(ctx, cmd, lockPath, false)
+	}()
+	<-started
+	time.Sleep(100 * time.Millisecond)
+
+	err := exec.RunLocked(ctx, exec.Command("true"), lockPath, false)
+	if !errors.Is(err, exec.ErrLocked) {
+		t.Errorf("Expected ErrLocked, got %v", err)
+	}
+	timeout, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	err = exec.RunLocked(timeout, exec.Command("true"), lockPath, true)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded, got %v", err)
+	}
+	start := time.Now()
+	if err := exec.RunLocked(ctx, exec.Command("true"), lockPath, true); err != nil {
+		t.Errorf("Expected to acquire the lock after waiting, got %v", err)
+	}
+	if time.Since(start) < 100*time.Millisecond {
+		t.Error("Expected to wait for the lock")
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,48 @@
+package exec
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"regexp"
+)
+
+// LevelPattern maps lines of output matching Pattern to Level.
+type LevelPattern struct {
+	Pattern *regexp.Regexp
+	Level   slog.Level
+}
+
+// DefaultLevelPatterns recognise common spellings of log levels in tool output. Lines matching none are logged at
+// slog.LevelInfo.
+var DefaultLevelPatterns = []LevelPattern{
+	{regexp.MustCompile(`(?i)\b(error|fatal|panic|critical)\b`), slog.LevelError},
+	{regexp.MustCompile(`(?i)\bwarn(ing)?\b`), slog.LevelWarn},
+	{regexp.MustCompile(`(?i)\b(debug|trace)\b`), slog.LevelDebug},
+}
+
+// LogStderr sends each line the command writes to stderr to logger, instead of to Stderr, at the level of the first of
+// patterns that matches it. If patterns is nil, DefaultLevelPatterns is used. It must be called before Start.
+//
+// Each record has a "cmd" attribute with the name of the command.
+func LogStderr(cmd *Cmd, logger *slog.Logger, patterns []LevelPattern) {
+	if patterns == nil {
+		patterns = DefaultLevelPatterns
+	}
+	name := ""
+	if len(cmd.Args) > 0 {
+		name = cmd.Args[0]
+	}
+	cmd.Stderr = &filterWriter{filter: func(line []byte) []byte {
+		line = bytes.TrimRight(line, "\r\n")
+		level := slog.LevelInfo
+		for _, pattern := range patterns {
+			if pattern.Pattern.Match(line) {
+				level = pattern.Level
+				break
+			}
+		}
+		logger.Log(context.Background(), level, string(line), "cmd", name)
+		return nil
+	}}
+}
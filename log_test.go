@@ -0,0 +1,34 @@
+//go:build (linux || darwin) && (amd64 || arm64)
+
+package exec_test
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+
+	"github.com/alecthomas/exec"
+)
+
+func TestLogStderr(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug, ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+		if a.Key == slog.TimeKey {
+			return slog.Attr{}
+		}
+		return a
+	}}))
+	cmd := exec.Command("sh", "-c", `echo "ERROR: disk full" >&2; echo "warning: deprecated" >&2; echo "started" >&2; printf "debug: done" >&2`)
+	exec.LogStderr(cmd, logger, nil)
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	expected := `level=ERROR msg="ERROR: disk full" cmd=sh
+level=WARN msg="warning: deprecated" cmd=sh
+level=INFO msg=started cmd=sh
+level=DEBUG msg="debug: done" cmd=sh
+`
+	if logs.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, logs.String())
+	}
+}
@@ -0,0 +1,77 @@
+package exec
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"sync"
+)
+
+// maxLineLength bounds the memory used to buffer a partial line of output. Longer lines are split.
+const maxLineLength = 64 * 1024
+
+// MatchOutput calls fn with the submatches of each match of pattern in the command's stdout and stderr, as it is
+// written. It must be called after Stdout and Stderr are set and before Start, and may be called more than once.
+//
+// Patterns are matched against one line at a time, so a match cannot span lines, but a line can span any number of
+// writes by the child. Output is passed through to Stdout and Stderr unchanged. fn is called synchronously, one call
+// at a time, so it should not block. A final line without a trailing newline is not matched.
+func MatchOutput(cmd *Cmd, pattern *regexp.Regexp, fn func(match []string)) {
+	var lock sync.Mutex
+	onLine := func(line []byte) {
+		lock.Lock()
+		defer lock.Unlock()
+		for _, match := range pattern.FindAllSubmatch(line, -1) {
+			strs := make([]string, len(match))
+			for i, group := range match {
+				strs[i] = string(group)
+			}
+			fn(strs)
+		}
+	}
+	same := cmd.Stdout == cmd.Stderr
+	cmd.Stdout = newLineWriter(cmd.Stdout, onLine)
+	if same {
+		cmd.Stderr = cmd.Stdout
+	} else {
+		cmd.Stderr = newLineWriter(cmd.Stderr, onLine)
+	}
+}
+
+// lineWriter passes writes through to w, and calls fn with each complete line, excluding the line terminator.
+type lineWriter struct {
+	w       io.Writer
+	fn      func(line []byte)
+	pending []byte
+}
+
+func newLineWriter(w io.Writer, fn func(line []byte)) *lineWriter {
+	if w == nil {
+		w = io.Discard
+	}
+	return &lineWriter{w: w, fn: fn}
+}
+
+func (l *lineWriter) Write(p []byte) (int, error) {
+	n, err := l.w.Write(p)
+	data := p[:n]
+	for len(data) > 0 {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			l.pending = append(l.pending, data...)
+			if len(l.pending) >= maxLineLength {
+				l.fn(l.pending)
+				l.pending = l.pending[:0]
+			}
+			break
+		}
+		line := data[:i]
+		if len(l.pending) > 0 {
+			line = append(l.pending, line...)
+			l.pending = l.pending[:0]
+		}
+		l.fn(bytes.TrimSuffix(line, []byte("\r")))
+		data = data[i+1:]
+	}
+	return n, err
+}
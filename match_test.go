@@ -0,0 +1,34 @@
+//go:build (linux || darwin) && (amd64 || arm64)
+
+package exec_test
+
+import (
+	"bytes"
+	"regexp"
+	"sort"
+	"testing"
+
+	"github.com/alecthomas/exec"
+)
+
+func TestMatchOutput(t *testing.T) {
+	var stdout bytes.Buffer
+	// The port is written in two parts, to check that matches spanning writes are found.
+	cmd := exec.Command("sh", "-c", `printf 'starting\nListening on :80'; sleep 0.1; printf '80\n'; echo "Listening on :9090" >&2`)
+	cmd.Stdout = &stdout
+	var ports []string
+	exec.MatchOutput(cmd, regexp.MustCompile(`Listening on :(\d+)`), func(match []string) {
+		ports = append(ports, match[1])
+	})
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	// Stdout and stderr are copied concurrently, so matches across them are unordered.
+	sort.Strings(ports)
+	if len(ports) != 2 || ports[0] != "8080" || ports[1] != "9090" {
+		t.Errorf("Expected ports [8080 9090], got %v", ports)
+	}
+	if expected := "starting\nListening on :8080\n"; stdout.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, stdout.String())
+	}
+}
@@ -0,0 +1,83 @@
+package exec
+
+import (
+	"errors"
+)
+
+// Meaning is the interpretation of a command's exit code.
+type Meaning int
+
+const (
+	// MeaningFailure means the command failed.
+	MeaningFailure Meaning = iota
+	// MeaningSuccess means the command succeeded.
+	MeaningSuccess
+	// MeaningNegative means the command succeeded with a negative result, such as grep finding no matches or diff
+	// finding differences.
+	MeaningNegative
+)
+
+func (m Meaning) String() string {
+	switch m {
+	case MeaningSuccess:
+		return "success"
+	case MeaningNegative:
+		return "negative"
+	default:
+		return "failure"
+	}
+}
+
+var (
+	// GrepExitCodes are the exit code meanings of grep: 1 means no lines were selected.
+	GrepExitCodes = map[int]Meaning{1: MeaningNegative}
+	// DiffExitCodes are the exit code meanings of diff and cmp: 1 means the inputs differ.
+	DiffExitCodes = map[int]Meaning{1: MeaningNegative}
+)
+
+// Outcome is the result of a command whose exit codes have been interpreted by RunWithMeaning or ClassifyExit.
+type Outcome struct {
+	// ExitCode of the command, or -1 if it did not exit normally.
+	ExitCode int
+	Meaning  Meaning
+}
+
+// Success returns true if the command succeeded, with either a positive or a negative result.
+func (o Outcome) Success() bool {
+	return o.Meaning != MeaningFailure
+}
+
+// RunWithMeaning runs cmd and interprets its exit code using meanings.
+//
+// Exit code 0 means success unless meanings says otherwise, and any other code not in meanings is a failure. An error
+// is returned only for a failure, so that eg. grep finding no matches is not treated as an error.
+func RunWithMeaning(cmd *Cmd, meanings map[int]Meaning) (Outcome, error) {
+	return ClassifyExit(cmd.Run(), meanings)
+}
+
+// ClassifyExit interprets the error returned by Run or Wait using meanings, as RunWithMeaning does.
+func ClassifyExit(err error, meanings map[int]Meaning) (Outcome, error) {
+	code := 0
+	if err != nil {
+		var exitErr *ExitError
+		if !errors.As(err, &exitErr) || exitErr.ExitCode() < 0 {
+			return Outcome{ExitCode: -1, Meaning: MeaningFailure}, err
+		}
+		code = exitErr.ExitCode()
+	}
+	meaning, ok := meanings[code]
+	if !ok {
+		meaning = MeaningFailure
+		if code == 0 {
+			meaning = MeaningSuccess
+		}
+	}
+	outcome := Outcome{ExitCode: code, Meaning: meaning}
+	if meaning != MeaningFailure {
+		return outcome, nil
+	}
+	if err == nil {
+		err = errors.New("exec: exit status 0 is a failure for this command")
+	}
+	return outcome, err
+}
@@ -0,0 +1,34 @@
+//go:build (linux || darwin) && (amd64 || arm64)
+
+package exec_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/exec"
+)
+
+func TestRunWithMeaning(t *testing.T) {
+	tests := []struct {
+		pattern string
+		code    int
+		meaning exec.Meaning
+		err     bool
+	}{
+		{"b", 0, exec.MeaningSuccess, false},
+		{"z", 1, exec.MeaningNegative, false},
+		{"[", 2, exec.MeaningFailure, true},
+	}
+	for _, test := range tests {
+		cmd := exec.Command("grep", test.pattern)
+		cmd.Stdin = strings.NewReader("a\nb\n")
+		outcome, err := exec.RunWithMeaning(cmd, exec.GrepExitCodes)
+		if (err != nil) != test.err {
+			t.Errorf("grep %q: unexpected error %v", test.pattern, err)
+		}
+		if outcome.ExitCode != test.code || outcome.Meaning != test.meaning {
+			t.Errorf("grep %q: expected %d (%s), got %d (%s)", test.pattern, test.code, test.meaning, outcome.ExitCode, outcome.Meaning)
+		}
+	}
+}
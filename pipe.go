@@ -0,0 +1,376 @@
+package exec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Pipeline runs commands connected like a shell pipeline, with the stdout of each stage connected directly to the
+// stdin of the next by an OS pipe.
+//
+// When the pipeline's context is done, it is torn down from the first stage onwards rather than killed all at once, so
+// that downstream stages can drain buffered data: the pipe feeding the first stage is closed, then each stage in turn
+// is given its grace period (see SetGracePeriod) to exit by itself, sent SIGTERM, and finally sent SIGKILL if it is
+// still running after another grace period. Stages should therefore be created without a context, eg. with Command.
+//
+// The pipeline feeds the first stage itself if its Stdin is not an *os.File. As with os/exec, Wait then also waits for
+// the Stdin to be copied, and teardown closes the Stdin if it is an io.Closer so that the copy ends. An *os.File Stdin
+// is passed to the first stage directly, so it belongs to the caller and is not closed.
+//
+// Each command stage is an ordinary command, so it has its own Dir, Env and Stderr, and its own timeout (see
+// SetTimeout) and grace period, while the pipeline's context applies to them all. Stages created with Func, Tee and
+// Buffer run in this process instead, and are stopped by closing their input and output.
+type Pipeline struct {
+	// Grace is the grace period for stages that do not have their own.
+	Grace time.Duration
+	// PipeSize sets the capacity in bytes of each OS pipe between stages, to trade memory for fewer context switches in
+	// high-volume pipelines, eg. "pg_dump | zstd". Zero leaves the system default, which is 64KiB on Linux. It is only
+	// supported on Linux, where unprivileged processes are limited to /proc/sys/fs/pipe-max-size, 1MiB by default, and
+	// is ignored on macOS. Use Buffer to add more buffering to a single edge of the pipeline.
+	PipeSize int
+
+	ctx    context.Context
+	stages []*pipeStage
+	// stdin feeds the first stage from source if its Stdin is not an *os.File, so that teardown can close it. copied is
+	// closed once the copy has finished.
+	stdin   *os.File
+	source  io.Reader
+	copied  chan struct{}
+	started bool
+	stop    func() bool
+	torn    chan struct{}
+	// cancelled is set if the context was done while any stage was still running.
+	cancelled bool
+}
+
+// Stage is a stage of a Pipeline: a *Cmd, or a filter that runs in this process, created with Func, Tee or Buffer.
+type Stage interface {
+	newStage() *pipeStage
+}
+
+type pipeStage struct {
+	cmd *Cmd
+	fn  func(r io.Reader, w io.Writer) error
+	// r and w are a function stage's ends of the pipes either side of it, or nil at either end of the pipeline.
+	r, w   *os.File
+	pid    int
+	exited chan struct{}
+	err    error
+}
+
+func (c *Cmd) newStage() *pipeStage { return &pipeStage{cmd: c} }
+
+type funcStage func(r io.Reader, w io.Writer) error
+
+func (f funcStage) newStage() *pipeStage { return &pipeStage{fn: f} }
+
+// Func returns a pipeline stage that runs fn in a goroutine, reading the output of the previous stage from r and
+// writing the input of the next to w. The stage fails if fn returns an error.
+//
+// A Func at the start of the pipeline reads no input, and one at the end has its output discarded. fn should return
+// once reading from r or writing to w fails, as that is how it is stopped when the pipeline is torn down.
+func Func(fn func(r io.Reader, w io.Writer) error) Stage {
+	return funcStage(fn)
+}
+
+// Tee returns a pipeline stage that passes its input through unchanged while also writing it to side, eg. to log or
+// checksum a stream in the middle of a pipeline.
+func Tee(side io.Writer) Stage {
+	return Func(func(r io.Reader, w io.Writer) error {
+		_, err := io.Copy(io.MultiWriter(w, side), r)
+		return err
+	})
+}
+
+// bufferChunk is the largest read made by a Buffer stage.
+const bufferChunk = 32 * 1024
+
+// Buffer returns a pipeline stage that passes its input through unchanged, holding up to size bytes in memory, so
+// that a bursty producer is not blocked by a slow consumer beyond what the OS pipes either side of it can hold. Data is
+// copied through this process, which costs throughput on a steady stream, so it is only worthwhile for bursty ones.
+//
+// Input is read in chunks of up to 32KiB, or size if that is smaller, and besides the buffered chunks the stage holds
+// the one being read and the one being written, so its memory use is at most size plus two chunks.
+func Buffer(size int) Stage {
+	chunkSize := max(1, min(size, bufferChunk))
+	return Func(func(r io.Reader, w io.Writer) error {
+		chunks := make(chan []byte, max(1, size/chunkSize))
+		var readErr error
+		go func() {
+			defer close(chunks)
+			for {
+				chunk := make([]byte, chunkSize)
+				n, err := r.Read(chunk)
+				if n > 0 {
+					chunks <- chunk[:n]
+				}
+				if err != nil {
+					if err != io.EOF {
+						readErr = err
+					}
+					return
+				}
+			}
+		}()
+		for chunk := range chunks {
+			if _, err := w.Write(chunk); err != nil {
+				// Unblock the reader, which stops once r is closed as the stage exits.
+				go func() {
+					for range chunks {
+					}
+				}()
+				return err
+			}
+		}
+		return readErr
+	})
+}
+
+// Pipe returns a pipeline of stages, which are connected when it is started. The Stdin of the first stage and the
+// Stdout of the last are used as set; the Stdin and Stdout of every other command must be left unset.
+func Pipe(ctx context.Context, stages ...Stage) *Pipeline {
+	p := &Pipeline{ctx: ctx}
+	for _, stage := range stages {
+		p.stages = append(p.stages, stage.newStage())
+	}
+	return p
+}
+
+// Run starts the pipeline and waits for it to complete.
+func (p *Pipeline) Run() error {
+	if err := p.Start(); err != nil {
+		return err
+	}
+	return p.Wait()
+}
+
+// Start connects and starts every stage of the pipeline. If any stage fails to start, those already started are
+// killed.
+func (p *Pipeline) Start() error {
+	if len(p.stages) == 0 {
+		return errors.New("exec: empty pipeline")
+	}
+	if p.started {
+		return errors.New("exec: pipeline already started")
+	}
+	// The parent's copies of the commands' pipes must be closed once they have started, or no stage would see end of
+	// input.
+	var inherited []*os.File
+	defer func() {
+		for _, f := range inherited {
+			_ = f.Close()
+		}
+	}()
+	if first := p.stages[0].cmd; first != nil {
+		if _, ok := first.Stdin.(*os.File); !ok && first.Stdin != nil {
+			r, w, err := os.Pipe()
+			if err != nil {
+				return err
+			}
+			inherited = append(inherited, r)
+			p.source, first.Stdin, p.stdin = first.Stdin, r, w
+		}
+	}
+	for i := 1; i < len(p.stages); i++ {
+		r, w, err := os.Pipe()
+		if err == nil && p.PipeSize > 0 {
+			if err = setPipeSize(w, p.PipeSize); err != nil {
+				_ = r.Close()
+				_ = w.Close()
+			}
+		}
+		if err != nil {
+			p.abort()
+			return err
+		}
+		if prev := p.stages[i-1]; prev.cmd != nil {
+			prev.cmd.Stdout = w
+			inherited = append(inherited, w)
+		} else {
+			prev.w = w
+		}
+		if next := p.stages[i]; next.cmd != nil {
+			next.cmd.Stdin = r
+			inherited = append(inherited, r)
+		} else {
+			next.r = r
+		}
+	}
+	for i, stage := range p.stages {
+		if stage.cmd == nil {
+			continue
+		}
+		if err := stage.cmd.Start(); err != nil {
+			p.abort()
+			for _, started := range p.stages[:i] {
+				if started.cmd != nil {
+					markSignalled(started.cmd)
+					_ = signalGroup(started.pid, syscall.SIGKILL)
+					_ = started.cmd.Wait()
+				}
+			}
+			return fmt.Errorf("stage %d: %w", i, err)
+		}
+		stage.pid = stage.cmd.Process.Pid
+	}
+	p.started = true
+	if p.source != nil {
+		p.copied = make(chan struct{})
+		go func() {
+			defer close(p.copied)
+			_, _ = io.Copy(p.stdin, p.source)
+			p.closeStdin()
+		}()
+	}
+	for _, stage := range p.stages {
+		stage.exited = make(chan struct{})
+		go func() {
+			if stage.cmd != nil {
+				stage.err = stage.cmd.Wait()
+			} else {
+				stage.err = stage.run()
+			}
+			close(stage.exited)
+		}()
+	}
+	p.torn = make(chan struct{})
+	p.stop = context.AfterFunc(p.ctx, func() {
+		defer close(p.torn)
+		p.cancelled = !p.exitedAll()
+		p.teardown()
+	})
+	return nil
+}
+
+// Wait waits for every stage of the pipeline to exit, and returns the errors of those that failed, along with the
+// context's error if the pipeline was torn down while any stage was still running.
+func (p *Pipeline) Wait() error {
+	if !p.started {
+		return errors.New("exec: pipeline not started")
+	}
+	for _, stage := range p.stages {
+		<-stage.exited
+	}
+	if p.copied != nil {
+		<-p.copied
+	}
+	var errs []error
+	if !p.stop() {
+		<-p.torn
+		if p.cancelled {
+			errs = append(errs, context.Cause(p.ctx))
+		}
+	}
+	for i, stage := range p.stages {
+		if stage.err != nil {
+			errs = append(errs, fmt.Errorf("stage %d: %w", i, stage.err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// teardown stops the stages in order, waiting for each to exit before moving on to the next so that it sees the end
+// of its input.
+func (p *Pipeline) teardown() {
+	p.closeStdin()
+	if closer, ok := p.source.(io.Closer); ok {
+		_ = closer.Close()
+	}
+	for _, stage := range p.stages {
+		if !exitedWithin(stage.exited, p.grace(stage)) {
+			p.stopStage(stage)
+		}
+	}
+}
+
+// stopStage sends a command SIGTERM, then SIGKILL if it has not exited after its grace period, and waits for it to
+// exit. A function stage has its pipes closed instead.
+func (p *Pipeline) stopStage(stage *pipeStage) {
+	if stage.cmd == nil {
+		stage.closePipes()
+		<-stage.exited
+		return
+	}
+	markSignalled(stage.cmd)
+	_ = signalGroup(stage.pid, syscall.SIGTERM)
+	if !exitedWithin(stage.exited, p.grace(stage)) {
+		_ = signalGroup(stage.pid, syscall.SIGKILL)
+		<-stage.exited
+	}
+}
+
+func (p *Pipeline) grace(stage *pipeStage) time.Duration {
+	if stage.cmd != nil {
+		if grace := gracePeriod(stage.cmd); grace > 0 {
+			return grace
+		}
+	}
+	return p.Grace
+}
+
+// exitedAll reports whether every stage has exited.
+func (p *Pipeline) exitedAll() bool {
+	for _, stage := range p.stages {
+		select {
+		case <-stage.exited:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// abort releases the pipes of a pipeline that failed to start.
+func (p *Pipeline) abort() {
+	p.closeStdin()
+	for _, stage := range p.stages {
+		stage.closePipes()
+	}
+}
+
+func (p *Pipeline) closeStdin() {
+	if p.stdin != nil {
+		_ = p.stdin.Close()
+	}
+}
+
+// run runs a function stage, closing its pipes once it returns so that the stages either side of it see it exit.
+func (s *pipeStage) run() error {
+	defer s.closePipes()
+	var r io.Reader = strings.NewReader("")
+	if s.r != nil {
+		r = s.r
+	}
+	var w io.Writer = io.Discard
+	if s.w != nil {
+		w = s.w
+	}
+	return s.fn(r, w)
+}
+
+func (s *pipeStage) closePipes() {
+	if s.r != nil {
+		_ = s.r.Close()
+	}
+	if s.w != nil {
+		_ = s.w.Close()
+	}
+}
+
+// exitedWithin reports whether exited is closed within d.
+func exitedWithin(exited <-chan struct{}, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-exited:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
@@ -0,0 +1,10 @@
+//go:build amd64 || arm64
+
+package exec
+
+import "os"
+
+// setPipeSize has no effect, as macOS has no way to set the capacity of a pipe. Pipes grow on demand up to 64KiB.
+func setPipeSize(f *os.File, size int) error {
+	return nil
+}
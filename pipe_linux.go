@@ -0,0 +1,30 @@
+//go:build amd64 || arm64
+
+package exec
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+const fSetPipeSize = 0x407 // F_SETPIPE_SZ
+
+// setPipeSize sets the capacity of the pipe f to at least size bytes. The kernel rounds it up to a power of two
+// pages, and limits unprivileged processes to /proc/sys/fs/pipe-max-size.
+func setPipeSize(f *os.File, size int) error {
+	conn, err := f.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var errno syscall.Errno
+	if err := conn.Control(func(fd uintptr) {
+		_, _, errno = syscall.Syscall(syscall.SYS_FCNTL, fd, fSetPipeSize, uintptr(size))
+	}); err != nil {
+		return err
+	}
+	if errno != 0 {
+		return fmt.Errorf("F_SETPIPE_SZ: %w", errno)
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+//go:build (linux || darwin) && (amd64 || arm64)
+
+package exec_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/exec"
+)
+
+func TestPipe(t *testing.T) {
+	sort := exec.Command("sort")
+	var stdout bytes.Buffer
+	sort.Stdout = &stdout
+	first := exec.Command("cat")
+	first.Stdin = strings.NewReader("b\na\n")
+	if err := exec.Pipe(context.Background(), first, exec.Command("cat"), sort).Run(); err != nil {
+		t.Fatal(err)
+	}
+	if stdout.String() != "a\nb\n" {
+		t.Errorf("Expected %q, got %q", "a\nb\n", stdout.String())
+	}
+
+	err := exec.Pipe(context.Background(), exec.Command("false"), exec.Command("cat")).Run()
+	if err == nil || !strings.Contains(err.Error(), "stage 0") {
+		t.Errorf("Expected stage 0 to fail, got %v", err)
+	}
+}
+
+func TestPipeTeardown(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	last := exec.Command("sh", "-c", "cat; echo drained")
+	var stdout bytes.Buffer
+	last.Stdout = &stdout
+	var side bytes.Buffer
+	pipeline := exec.Pipe(ctx, exec.Command("sh", "-c", "echo data; exec sleep 10"), exec.Tee(&side), last)
+	pipeline.Grace = 100 * time.Millisecond
+	if err := pipeline.Start(); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	cancel()
+	if err := pipeline.Wait(); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected %v, got %v", context.Canceled, err)
+	}
+	// The last stage must have been left to drain its input rather than killed with the first.
+	if stdout.String() != "data\ndrained\n" {
+		t.Errorf("Expected %q, got %q", "data\ndrained\n", stdout.String())
+	}
+	if side.String() != "data\n" {
+		t.Errorf("Expected %q, got %q", "data\n", side.String())
+	}
+}
+
+func TestPipeTeardownClosesStdin(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	stdin, stdinWriter := io.Pipe()
+	first := exec.Command("cat")
+	first.Stdin = stdin
+	last := exec.Command("sh", "-c", "cat; echo drained")
+	var stdout bytes.Buffer
+	last.Stdout = &stdout
+	pipeline := exec.Pipe(ctx, first, last)
+	pipeline.Grace = 10 * time.Second
+	if err := pipeline.Start(); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	cancel()
+	if err := pipeline.Wait(); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected %v, got %v", context.Canceled, err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected closing stdin to end the pipeline, took %s", elapsed)
+	}
+	if stdout.String() != "drained\n" {
+		t.Errorf("Expected %q, got %q", "drained\n", stdout.String())
+	}
+	// The blocked Stdin must have been closed, so that the goroutine copying it has finished.
+	if _, err := stdinWriter.Write([]byte("x")); !errors.Is(err, io.ErrClosedPipe) {
+		t.Errorf("Expected %v, got %v", io.ErrClosedPipe, err)
+	}
+}
+
+func TestPipeCancelledAfterExit(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	pipeline := exec.Pipe(ctx, exec.Command("true"), exec.Command("cat"))
+	if err := pipeline.Start(); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(300 * time.Millisecond)
+	cancel()
+	if err := pipeline.Wait(); err != nil {
+		t.Errorf("Expected no error once every stage had exited, got %v", err)
+	}
+}
+
+func TestPipeStageOptions(t *testing.T) {
+	dir := t.TempDir()
+	first := exec.Command("sh", "-c", `echo "$STAGE" "$(pwd -P)"`)
+	first.Dir = dir
+	first.Env = append(os.Environ(), "STAGE=first")
+	last := exec.Command("sh", "-c", "cat; sleep 10")
+	var stdout bytes.Buffer
+	last.Stdout = &stdout
+	exec.SetTimeout(last, 200*time.Millisecond)
+	err := exec.Pipe(context.Background(), first, last).Run()
+	if err == nil || !strings.Contains(err.Error(), "stage 1: timed out") {
+		t.Errorf("Expected the last stage to time out, got %v", err)
+	}
+	wd, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "first " + wd + "\n"; stdout.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, stdout.String())
+	}
+}
+
+func TestPipeFuncAndTee(t *testing.T) {
+	upper := exec.Func(func(r io.Reader, w io.Writer) error {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(bytes.ToUpper(data))
+		return err
+	})
+	var side bytes.Buffer
+	last := exec.Command("cat")
+	var stdout bytes.Buffer
+	last.Stdout = &stdout
+	err := exec.Pipe(context.Background(), exec.Command("printf", "a\nb\n"), upper, exec.Tee(&side), last).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stdout.String() != "A\nB\n" {
+		t.Errorf("Expected %q, got %q", "A\nB\n", stdout.String())
+	}
+	if side.String() != "A\nB\n" {
+		t.Errorf("Expected %q, got %q", "A\nB\n", side.String())
+	}
+
+	failed := exec.Func(func(r io.Reader, w io.Writer) error { return errors.New("filter failed") })
+	err = exec.Pipe(context.Background(), exec.Command("echo"), failed, exec.Command("cat")).Run()
+	if err == nil || !strings.Contains(err.Error(), "stage 1: filter failed") {
+		t.Errorf("Expected stage 1 to fail, got %v", err)
+	}
+}
+
+func TestPipeBuffering(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789abcdef"), 64*1024)
+	first := exec.Command("cat")
+	first.Stdin = bytes.NewReader(data)
+	last := exec.Command("cat")
+	var stdout bytes.Buffer
+	last.Stdout = &stdout
+	pipeline := exec.Pipe(context.Background(), first, exec.Buffer(256*1024), last)
+	pipeline.PipeSize = 256 * 1024
+	if err := pipeline.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(stdout.Bytes(), data) {
+		t.Errorf("Expected %d bytes to pass through unchanged, got %d", len(data), stdout.Len())
+	}
+}
+
+func TestPipeSmallBuffer(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789abcdef"), 4*1024)
+	for _, size := range []int{1, 100} {
+		first := exec.Command("cat")
+		first.Stdin = bytes.NewReader(data)
+		var stdout bytes.Buffer
+		last := exec.Command("cat")
+		last.Stdout = &stdout
+		if err := exec.Pipe(context.Background(), first, exec.Buffer(size), last).Run(); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(stdout.Bytes(), data) {
+			t.Errorf("Expected %d bytes through a %d byte buffer, got %d", len(data), size, stdout.Len())
+		}
+	}
+}
@@ -0,0 +1,25 @@
+//go:build amd64 || arm64
+
+package exec
+
+import (
+	"os"
+	"syscall"
+)
+
+const (
+	accessExecute = 0x1 // X_OK
+	mntNoExec     = 0x4 // MNT_NOEXEC
+)
+
+// execPolicy returns a description of the policy likely to be preventing execution from dir, or "".
+func execPolicy(dir string) string {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(dir, &stat); err == nil && stat.Flags&mntNoExec != 0 {
+		return "a noexec mount"
+	}
+	if os.Getenv("APP_SANDBOX_CONTAINER_ID") != "" {
+		return "the App Sandbox"
+	}
+	return ""
+}
@@ -0,0 +1,29 @@
+//go:build amd64 || arm64
+
+package exec
+
+import (
+	"os"
+	"strings"
+	"syscall"
+)
+
+const (
+	accessExecute = 0x1 // X_OK
+	stNoExec      = 0x8 // ST_NOEXEC
+)
+
+// execPolicy returns a description of the policy likely to be preventing execution from dir, or "".
+func execPolicy(dir string) string {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(dir, &stat); err == nil && stat.Flags&stNoExec != 0 {
+		return "a noexec mount"
+	}
+	if enforce, err := os.ReadFile("/sys/fs/selinux/enforce"); err == nil && strings.TrimSpace(string(enforce)) == "1" {
+		return "SELinux (enforcing)"
+	}
+	if enabled, err := os.ReadFile("/sys/module/apparmor/parameters/enabled"); err == nil && strings.TrimSpace(string(enabled)) == "Y" {
+		return "AppArmor"
+	}
+	return ""
+}
@@ -0,0 +1,66 @@
+package exec
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Prepare checks that cmd can be started, without starting it, and returns every problem found.
+//
+// It reports errors from creating the command, an executable that cannot be resolved, and a working directory that
+// does not exist. The intermediary is extracted when the command is created, so a command that passes Prepare fails
+// to start only if its environment changes in the meantime. Call Commit to start it.
+func Prepare(cmd *Cmd) error {
+	if cmd.Err != nil {
+		return cmd.Err
+	}
+	if cmd.Process != nil {
+		return errors.New("exec: already started")
+	}
+	var errs []error
+	dir := cmd.Dir
+	if dir == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		dir = wd
+	} else if info, err := os.Stat(dir); err != nil {
+		errs = append(errs, fmt.Errorf("working directory: %w", err))
+	} else if !info.IsDir() {
+		errs = append(errs, fmt.Errorf("working directory: %s is not a directory", dir))
+	}
+	if len(cmd.Args) == 0 {
+		errs = append(errs, errors.New("exec: no command"))
+	} else if _, err := cmd.resolveBinary(dir); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// PrepareAll calls Prepare on each command, so that every configuration error in a batch can be reported before any
+// command is run. Each error is prefixed with the index and name of its command.
+func PrepareAll(cmds ...*Cmd) error {
+	var errs []error
+	for i, cmd := range cmds {
+		if err := Prepare(cmd); err != nil {
+			name := ""
+			if len(cmd.Args) > 0 {
+				name = cmd.Args[0]
+			}
+			errs = append(errs, fmt.Errorf("command %d (%s): %w", i, name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Commit starts a command checked with Prepare, completing the two-phase start. It repeats Prepare's checks first, as
+// the environment may have changed since, so that a command that can no longer start reports the same errors as
+// Prepare rather than the less specific error from Start.
+func (c *Cmd) Commit() error {
+	if err := Prepare(c); err != nil {
+		return err
+	}
+	return c.Start()
+}
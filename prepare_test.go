@@ -0,0 +1,70 @@
+//go:build (linux || darwin) && (amd64 || arm64)
+
+package exec_test
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/exec"
+)
+
+func TestPrepare(t *testing.T) {
+	cmd := exec.Command("echo", "hello")
+	if err := exec.Prepare(cmd); err != nil {
+		t.Fatalf("Expected valid command, got %v", err)
+	}
+	if err := cmd.Commit(); err != nil {
+		t.Fatalf("Command failed to start after Prepare: %v", err)
+	}
+	if err := cmd.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if err := exec.Prepare(cmd); err == nil {
+		t.Error("Expected error preparing a started command")
+	}
+	if err := cmd.Commit(); err == nil {
+		t.Error("Expected error committing a started command")
+	}
+}
+
+func TestCommitRechecks(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "dir")
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command("echo")
+	cmd.Dir = dir
+	if err := exec.Prepare(cmd); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Commit(); !errors.Is(err, fs.ErrNotExist) || !strings.Contains(err.Error(), "working directory") {
+		t.Errorf("Expected missing working directory, got %v", err)
+	}
+}
+
+func TestPrepareAll(t *testing.T) {
+	missingDir := exec.Command("echo")
+	missingDir.Dir = filepath.Join(t.TempDir(), "missing")
+	err := exec.PrepareAll(
+		exec.Command("echo"),
+		exec.Command("definitely-not-a-real-command"),
+		missingDir,
+	)
+	if err == nil {
+		t.Fatal("Expected errors")
+	}
+	if !errors.Is(err, exec.ErrNotFound) || !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected both errors to be reported, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "command 1 (definitely-not-a-real-command)") || !strings.Contains(err.Error(), "command 2 (echo)") {
+		t.Errorf("Expected errors to identify commands, got %v", err)
+	}
+}
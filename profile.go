@@ -0,0 +1,62 @@
+package exec
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+)
+
+// ProfileOptions configures Profile.
+type ProfileOptions struct {
+	// Dir the profile is written to. Defaults to the system temporary directory.
+	Dir string
+	// Args are additional arguments for the profiler, eg. "-g" for perf record to record call graphs.
+	Args []string
+}
+
+// Profile runs the command under the platform's sampling profiler, and returns the path the profile will be written
+// to when the command exits. It is also reported in the Result, if the command is run with RunResult.
+//
+// On Linux this is "perf record", which writes a perf.data file for "perf report". On macOS it is Instruments' Time
+// Profiler template via "xctrace record", which writes a .trace bundle. The profiler must be installed, and as it
+// becomes the child of the intermediary, it rather than the command is terminated if this process dies. This
+// rewrites Path and Args, so it must be called after they are final.
+func Profile(cmd *Cmd, opts ProfileOptions) (path string, err error) {
+	dir := opts.Dir
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	name := fmt.Sprintf("%s-%d", filepath.Base(cmd.Path), time.Now().UnixNano())
+	var profiler string
+	var args []string
+	switch runtime.GOOS {
+	case "linux":
+		path = filepath.Join(dir, name+".perf.data")
+		profiler = "perf"
+		args = append([]string{"perf", "record", "--output", path}, opts.Args...)
+	case "darwin":
+		path = filepath.Join(dir, name+".trace")
+		profiler = "xctrace"
+		args = append([]string{"xcrun", "xctrace", "record", "--template", "Time Profiler", "--output", path}, opts.Args...)
+		args = append(args, "--launch")
+	default:
+		return "", fmt.Errorf("exec: profiling is not supported on %s", runtime.GOOS)
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	profilerPath, err := LookPath(args[0])
+	if err != nil {
+		return "", fmt.Errorf("exec: %s is required to profile commands: %w", profiler, err)
+	}
+	args = append(args, "--", cmd.Path)
+	if len(cmd.Args) > 1 {
+		args = append(args, cmd.Args[1:]...)
+	}
+	cmd.Path = profilerPath
+	cmd.Args = args
+	cmd.profile = path
+	return path, nil
+}
@@ -0,0 +1,42 @@
+//go:build (linux || darwin) && (amd64 || arm64)
+
+package exec_test
+
+import (
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/alecthomas/exec"
+)
+
+func TestProfile(t *testing.T) {
+	profiler := "perf"
+	if runtime.GOOS == "darwin" {
+		profiler = "xcrun"
+	}
+	// Records an empty profile, then runs the command.
+	fakeTool(t, profiler, `while [ "$1" != "--" ]; do
+  if [ "$1" = "--output" ]; then touch "$2"; fi
+  shift
+done
+shift
+exec "$@"`)
+
+	dir := t.TempDir()
+	cmd := exec.Command("echo", "profiled")
+	path, err := exec.Profile(cmd, exec.ProfileOptions{Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Dir(path) != dir {
+		t.Errorf("Expected profile in %s, got %s", dir, path)
+	}
+	result := exec.RunResult(cmd)
+	if result.Err != nil {
+		t.Fatal(result.Err)
+	}
+	if result.Profile != path {
+		t.Errorf("Expected result to report profile %s, got %q", path, result.Profile)
+	}
+}
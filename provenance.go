@@ -0,0 +1,242 @@
+package exec
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Provenance is a record of a single command execution, suitable for building supply-chain attestations.
+//
+// All digests are of the form "sha256:<hex>".
+type Provenance struct {
+	Binary       string      `json:"binary"`
+	BinaryDigest string      `json:"binaryDigest"`
+	Args         []string    `json:"args"`
+	EnvDigest    string      `json:"envDigest"`
+	EnvDiff      []EnvChange `json:"envDiff,omitempty"`
+	Dir          string      `json:"dir"`
+	DirDigest    string      `json:"dirDigest"`
+	OutputDigest string      `json:"outputDigest"`
+	Started      time.Time   `json:"started"`
+	Finished     time.Time   `json:"finished"`
+	ExitCode     int         `json:"exitCode"`
+	Signature    []byte      `json:"signature,omitempty"`
+}
+
+// Sign the record with key, replacing any existing signature.
+func (p *Provenance) Sign(key ed25519.PrivateKey) error {
+	payload, err := p.payload()
+	if err != nil {
+		return err
+	}
+	p.Signature = ed25519.Sign(key, payload)
+	return nil
+}
+
+// Verify the record's signature against key.
+func (p *Provenance) Verify(key ed25519.PublicKey) bool {
+	if len(p.Signature) == 0 {
+		return false
+	}
+	payload, err := p.payload()
+	if err != nil {
+		return false
+	}
+	return ed25519.Verify(key, payload, p.Signature)
+}
+
+// payload is the canonical encoding of the record that is signed.
+func (p *Provenance) payload() ([]byte, error) {
+	unsigned := *p
+	unsigned.Signature = nil
+	return json.Marshal(unsigned)
+}
+
+// ProvenanceSink receives provenance records.
+type ProvenanceSink interface {
+	Emit(record *Provenance) error
+}
+
+// ProvenanceSinkFunc is a function that implements ProvenanceSink.
+type ProvenanceSinkFunc func(record *Provenance) error
+
+func (f ProvenanceSinkFunc) Emit(record *Provenance) error { return f(record) }
+
+// JSONProvenanceSink returns a ProvenanceSink that writes each record to w as a line of JSON.
+func JSONProvenanceSink(w io.Writer) ProvenanceSink {
+	var lock sync.Mutex
+	enc := json.NewEncoder(w)
+	return ProvenanceSinkFunc(func(record *Provenance) error {
+		lock.Lock()
+		defer lock.Unlock()
+		return enc.Encode(record)
+	})
+}
+
+// RunWithProvenance runs cmd and emits a provenance record for it to sink.
+//
+// If key is non-nil the record is signed. The record is emitted even if the command fails, in which case the command's
+// error is returned. Note that computing the digest of the working directory reads every file beneath it.
+func RunWithProvenance(cmd *Cmd, key ed25519.PrivateKey, sink ProvenanceSink) error {
+	record := &Provenance{Args: cmd.Args, Dir: cmd.Dir}
+	if record.Dir == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		record.Dir = wd
+	}
+	binary, err := cmd.resolveBinary(record.Dir)
+	if err != nil {
+		return err
+	}
+	record.Binary = binary
+	if record.BinaryDigest, err = fileDigest(binary); err != nil {
+		return err
+	}
+	env := cmd.Env
+	if env == nil {
+		env = os.Environ()
+	}
+	record.EnvDigest = envDigest(env)
+	record.EnvDiff = EnvDiff(cmd)
+	if record.DirDigest, err = dirDigest(record.Dir); err != nil {
+		return err
+	}
+
+	output := sha256.New()
+	if cmd.Stdout == nil {
+		cmd.Stdout = output
+	} else {
+		cmd.Stdout = io.MultiWriter(cmd.Stdout, output)
+	}
+
+	record.Started = time.Now().UTC()
+	runErr := cmd.Run()
+	record.Finished = time.Now().UTC()
+	record.OutputDigest = formatDigest(output)
+	record.ExitCode = -1
+	if cmd.ProcessState != nil {
+		record.ExitCode = cmd.ProcessState.ExitCode()
+	}
+
+	if key != nil {
+		if err := record.Sign(key); err != nil {
+			return errors.Join(runErr, err)
+		}
+	}
+	if err := sink.Emit(record); err != nil {
+		return errors.Join(runErr, fmt.Errorf("emit provenance: %w", err))
+	}
+	return runErr
+}
+
+// defaultExecPath is the search path execvp uses if the environment has no PATH.
+const defaultExecPath = "/usr/bin:/bin"
+
+// resolveBinary resolves the program the command runs, as it will be resolved when the command is started from dir.
+//
+// That is Path if it has been changed or the command is run directly, and otherwise Args[0], which the intermediary
+// looks up itself using the PATH in the command's environment.
+func (c *Cmd) resolveBinary(dir string) (string, error) {
+	name := c.Path
+	if !c.direct && c.Path == c.path && len(c.Args) > 0 {
+		name = c.Args[0]
+	}
+	if strings.Contains(name, "/") {
+		if !filepath.IsAbs(name) {
+			name = filepath.Join(dir, name)
+		}
+		return name, nil
+	}
+	if c.Env == nil {
+		return LookPath(name)
+	}
+	path, ok := envMap(c.Env)["PATH"]
+	if !ok {
+		path = defaultExecPath
+	}
+	for _, entry := range filepath.SplitList(path) {
+		candidate := filepath.Join(entry, name)
+		if !filepath.IsAbs(candidate) {
+			candidate = filepath.Join(dir, candidate)
+		}
+		if info, err := os.Stat(candidate); err == nil && info.Mode().IsRegular() && info.Mode().Perm()&0o111 != 0 {
+			return candidate, nil
+		}
+	}
+	return "", &exec.Error{Name: name, Err: ErrNotFound}
+}
+
+func formatDigest(h hash.Hash) string {
+	return "sha256:" + hex.EncodeToString(h.Sum(nil))
+}
+
+func fileDigest(path string) (string, error) {
+	r, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close() //nolint
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return formatDigest(h), nil
+}
+
+func envDigest(env []string) string {
+	sorted := append([]string(nil), env...)
+	sort.Strings(sorted)
+	h := sha256.New()
+	for _, kv := range sorted {
+		h.Write([]byte(kv)) //nolint
+		h.Write([]byte{0})  //nolint
+	}
+	return formatDigest(h)
+}
+
+// dirDigest hashes the relative path, mode and content of every regular file beneath dir.
+func dirDigest(dir string) (string, error) {
+	h := sha256.New()
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		digest, err := fileDigest(path)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(h, "%s\x00%o\x00%s\n", filepath.ToSlash(rel), info.Mode().Perm(), digest)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return formatDigest(h), nil
+}
@@ -0,0 +1,112 @@
+//go:build (linux || darwin) && (amd64 || arm64)
+
+package exec_test
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alecthomas/exec"
+)
+
+func TestRunWithProvenance(t *testing.T) {
+	pub, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "input"), []byte("data"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, records bytes.Buffer
+	cmd := exec.Command("echo", "hello")
+	cmd.Dir = dir
+	cmd.Stdout = &stdout
+	if err := exec.RunWithProvenance(cmd, key, exec.JSONProvenanceSink(&records)); err != nil {
+		t.Fatalf("RunWithProvenance failed: %v", err)
+	}
+	if stdout.String() != "hello\n" {
+		t.Errorf("Expected output to still reach Stdout, got %q", stdout.String())
+	}
+
+	var record exec.Provenance
+	if err := json.Unmarshal(records.Bytes(), &record); err != nil {
+		t.Fatalf("Failed to decode record: %v", err)
+	}
+	sum := sha256.Sum256([]byte("hello\n"))
+	if expected := "sha256:" + hex.EncodeToString(sum[:]); record.OutputDigest != expected {
+		t.Errorf("Expected output digest %q, got %q", expected, record.OutputDigest)
+	}
+	if record.Dir != dir || record.DirDigest == "" || record.BinaryDigest == "" || record.EnvDigest == "" {
+		t.Errorf("Incomplete record: %+v", record)
+	}
+	if len(record.Args) != 2 || record.Args[0] != "echo" || record.Args[1] != "hello" {
+		t.Errorf("Expected args [echo hello], got %v", record.Args)
+	}
+	if !record.Verify(pub) {
+		t.Error("Expected signature to verify")
+	}
+	record.Args = append(record.Args, "tampered")
+	if record.Verify(pub) {
+		t.Error("Expected tampered record to fail verification")
+	}
+}
+
+func TestRunWithProvenanceFailure(t *testing.T) {
+	var got *exec.Provenance
+	cmd := exec.Command("false")
+	err := exec.RunWithProvenance(cmd, nil, exec.ProvenanceSinkFunc(func(record *exec.Provenance) error {
+		got = record
+		return nil
+	}))
+	if err == nil {
+		t.Fatal("Expected command to fail")
+	}
+	if got == nil {
+		t.Fatal("Expected a record to be emitted for a failed command")
+	}
+	if got.ExitCode != 1 {
+		t.Errorf("Expected exit code 1, got %d", got.ExitCode)
+	}
+	if got.Signature != nil {
+		t.Error("Expected unsigned record")
+	}
+}
+
+func TestRunWithProvenanceResolvesWithCommandPath(t *testing.T) {
+	bin := t.TempDir()
+	tool := filepath.Join(bin, "exec-test-tool")
+	if err := os.WriteFile(tool, []byte("#!/bin/sh\necho tool\n"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	var got *exec.Provenance
+	sink := exec.ProvenanceSinkFunc(func(record *exec.Provenance) error {
+		got = record
+		return nil
+	})
+
+	cmd := exec.Command("exec-test-tool")
+	cmd.Env = append(os.Environ(), "PATH="+bin)
+	if err := exec.RunWithProvenance(cmd, nil, sink); err != nil {
+		t.Fatal(err)
+	}
+	if got.Binary != tool {
+		t.Errorf("Expected %s, got %s", tool, got.Binary)
+	}
+
+	cmd = exec.Command("echo", "hello")
+	cmd.Path = tool
+	if err := exec.RunWithProvenance(cmd, nil, sink); err != nil {
+		t.Fatal(err)
+	}
+	if got.Binary != tool {
+		t.Errorf("Expected a changed Path to be recorded, got %s", got.Binary)
+	}
+}
@@ -0,0 +1,91 @@
+package exec
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// RateLimitPolicy determines what happens to output that exceeds the limit set by OutputRateLimit.
+type RateLimitPolicy int
+
+const (
+	// RateLimitBlock delays output until it is within the limit. Once the pipe buffer fills, the child blocks on write.
+	RateLimitBlock RateLimitPolicy = iota
+	// RateLimitDrop discards output in excess of the limit. The child is never slowed down.
+	RateLimitDrop
+)
+
+// OutputRateLimit limits the combined rate at which the command's Stdout and Stderr are written to bytesPerSec, with
+// bursts of up to one second's worth of output. It must be called after Stdout and Stderr are set and before Start.
+//
+// An *os.File is normally passed directly to the child, so setting a limit causes output to be copied by the parent
+// instead. Nil Stdout and Stderr are discarded by os/exec and are not affected.
+//
+// bytesPerSec must be positive; otherwise the command's Err is set, so that it fails to start.
+func OutputRateLimit(cmd *Cmd, bytesPerSec int, policy RateLimitPolicy) {
+	if bytesPerSec <= 0 {
+		cmd.Err = fmt.Errorf("exec: invalid output rate limit %d bytes per second", bytesPerSec)
+		return
+	}
+	limiter := &rateLimiter{rate: float64(bytesPerSec), tokens: float64(bytesPerSec), last: time.Now(), policy: policy}
+	same := cmd.Stdout == cmd.Stderr
+	if cmd.Stdout != nil {
+		cmd.Stdout = &rateLimitedWriter{w: cmd.Stdout, limiter: limiter}
+	}
+	if same {
+		cmd.Stderr = cmd.Stdout
+	} else if cmd.Stderr != nil {
+		cmd.Stderr = &rateLimitedWriter{w: cmd.Stderr, limiter: limiter}
+	}
+}
+
+// rateLimiter is a token bucket shared between the writers of a command.
+type rateLimiter struct {
+	lock   sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+	policy RateLimitPolicy
+}
+
+// take n bytes from the bucket. It returns how many bytes may be written, and how long to wait before writing them.
+func (l *rateLimiter) take(n int) (int, time.Duration) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	now := time.Now()
+	l.tokens = min(l.rate, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+	if l.policy == RateLimitDrop {
+		n = max(0, min(n, int(l.tokens)))
+		l.tokens -= float64(n)
+		return n, 0
+	}
+	// Reserve the tokens now, so that concurrent writers queue behind each other.
+	l.tokens -= float64(n)
+	if l.tokens >= 0 {
+		return n, 0
+	}
+	return n, time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+type rateLimitedWriter struct {
+	w       io.Writer
+	limiter *rateLimiter
+}
+
+func (r *rateLimitedWriter) Write(p []byte) (int, error) {
+	burst := max(1, int(r.limiter.rate))
+	for written := 0; written < len(p); {
+		chunk := p[written:min(len(p), written+burst)]
+		allowed, delay := r.limiter.take(len(chunk))
+		time.Sleep(delay)
+		if _, err := r.w.Write(chunk[:allowed]); err != nil {
+			return written, err
+		}
+		// Dropped output is reported as written, so that the child is not sent SIGPIPE.
+		written += len(chunk)
+	}
+	return len(p), nil
+}
@@ -0,0 +1,53 @@
+//go:build (linux || darwin) && (amd64 || arm64)
+
+package exec_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/exec"
+)
+
+func TestOutputRateLimitBlock(t *testing.T) {
+	var stdout bytes.Buffer
+	cmd := exec.Command("head", "-c", "30000", "/dev/zero")
+	cmd.Stdout = &stdout
+	exec.OutputRateLimit(cmd, 20000, exec.RateLimitBlock)
+	start := time.Now()
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if stdout.Len() != 30000 {
+		t.Errorf("Expected all 30000 bytes, got %d", stdout.Len())
+	}
+	// The first 20000 bytes are a burst, and the remainder takes half a second.
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Errorf("Expected output to be throttled, took %s", elapsed)
+	}
+}
+
+func TestOutputRateLimitDrop(t *testing.T) {
+	var stdout bytes.Buffer
+	cmd := exec.Command("head", "-c", "100000", "/dev/zero")
+	cmd.Stdout = &stdout
+	exec.OutputRateLimit(cmd, 20000, exec.RateLimitDrop)
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if stdout.Len() < 20000 || stdout.Len() >= 100000 {
+		t.Errorf("Expected excess output to be dropped, got %d bytes", stdout.Len())
+	}
+}
+
+func TestOutputRateLimitInvalid(t *testing.T) {
+	for _, rate := range []int{0, -1} {
+		cmd := exec.Command("true")
+		exec.OutputRateLimit(cmd, rate, exec.RateLimitBlock)
+		if err := cmd.Run(); err == nil || !strings.Contains(err.Error(), "invalid output rate limit") {
+			t.Errorf("Expected invalid rate %d to be rejected, got %v", rate, err)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+//go:build (linux || darwin) && (amd64 || arm64)
+
+package exec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+)
+
+// reExecReadyEnv holds the file descriptor a replacement started by ReExec uses to signal readiness.
+const reExecReadyEnv = "GO_EXEC_REEXEC_READY_FD"
+
+// ReExecOptions configures ReExec.
+type ReExecOptions struct {
+	// Args for the replacement, including argv[0]. Defaults to os.Args.
+	Args []string
+	// Env for the replacement. Defaults to os.Environ().
+	Env []string
+	// Files are passed to the replacement as file descriptors 3 onwards, eg. for handing off listening sockets.
+	Files []*os.File
+	// WaitReady waits for the replacement to call ReExecReady before returning. The wait is bounded by the context.
+	WaitReady bool
+}
+
+// ReExec starts a new instance of the current executable to replace this one, and returns its process.
+//
+// The caller is responsible for exiting once ReExec returns successfully. The replacement is deliberately not
+// supervised by an intermediary, as it must outlive this process, but any children started by this process through
+// this package will still be terminated when it exits. The replacement stays in this process's process group, so that
+// it can keep reading from and writing to a controlling terminal.
+//
+// If WaitReady is set and the replacement exits or the context is cancelled before it signals readiness, the
+// replacement is killed and an error is returned.
+func ReExec(ctx context.Context, opts ReExecOptions) (*os.Process, error) {
+	self, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	args := opts.Args
+	if args == nil {
+		args = os.Args
+	}
+	if len(args) == 0 {
+		return nil, errors.New("re-exec: no arguments, expected at least argv[0]")
+	}
+	env := opts.Env
+	if env == nil {
+		env = os.Environ()
+	}
+	cmd := exec.Command(self, args[1:]...)
+	cmd.Args[0] = args[0]
+	cmd.Env = env
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = opts.Files
+
+	if !opts.WaitReady {
+		if err := cmd.Start(); err != nil {
+			return nil, err
+		}
+		return cmd.Process, nil
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close() //nolint
+	cmd.ExtraFiles = append(append([]*os.File(nil), opts.Files...), w)
+	cmd.Env = append(append([]string(nil), env...), reExecReadyEnv+"="+strconv.Itoa(2+len(cmd.ExtraFiles)))
+	err = cmd.Start()
+	w.Close() //nolint
+	if err != nil {
+		return nil, err
+	}
+
+	ready := make(chan error, 1)
+	go func() {
+		_, err := r.Read(make([]byte, 1))
+		if errors.Is(err, io.EOF) {
+			err = errors.New("replacement exited before signalling readiness")
+		}
+		ready <- err
+	}()
+	select {
+	case err = <-ready:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	if err != nil {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return nil, fmt.Errorf("re-exec: %w", err)
+	}
+	return cmd.Process, nil
+}
+
+// ReExecReady signals to the process that started this one with ReExec that it is ready, eg. once it is serving on
+// its inherited listeners.
+//
+// It is a no-op if this process was not started by ReExec with WaitReady set.
+func ReExecReady() error {
+	value, ok := os.LookupEnv(reExecReadyEnv)
+	if !ok {
+		return nil
+	}
+	_ = os.Unsetenv(reExecReadyEnv)
+	fd, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", reExecReadyEnv, err)
+	}
+	w := os.NewFile(uintptr(fd), "reexec-ready")
+	defer w.Close() //nolint
+	_, err = w.Write([]byte{1})
+	return err
+}
@@ -0,0 +1,62 @@
+//go:build (linux || darwin) && (amd64 || arm64)
+
+package exec_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/exec"
+)
+
+const reExecHelperEnv = "EXEC_TEST_REEXEC_HELPER"
+
+func TestReExecHelper(t *testing.T) {
+	switch os.Getenv(reExecHelperEnv) {
+	case "ready":
+		if err := exec.ReExecReady(); err != nil {
+			os.Exit(2)
+		}
+		time.Sleep(10 * time.Second)
+		os.Exit(0)
+	case "exit":
+		os.Exit(3)
+	}
+}
+
+func TestReExec(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	process, err := exec.ReExec(ctx, exec.ReExecOptions{
+		Args:      []string{os.Args[0], "-test.run=^TestReExecHelper$"},
+		Env:       append(os.Environ(), reExecHelperEnv+"=ready"),
+		WaitReady: true,
+	})
+	if err != nil {
+		t.Fatalf("ReExec failed: %v", err)
+	}
+	_ = process.Kill()
+	_, _ = process.Wait()
+}
+
+func TestReExecNotReady(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := exec.ReExec(ctx, exec.ReExecOptions{
+		Args:      []string{os.Args[0], "-test.run=^TestReExecHelper$"},
+		Env:       append(os.Environ(), reExecHelperEnv+"=exit"),
+		WaitReady: true,
+	})
+	if err == nil {
+		t.Fatal("Expected ReExec to fail when the replacement exits before signalling readiness")
+	}
+}
+
+func TestReExecEmptyArgs(t *testing.T) {
+	_, err := exec.ReExec(context.Background(), exec.ReExecOptions{Args: []string{}})
+	if err == nil {
+		t.Fatal("Expected ReExec to fail without arguments")
+	}
+}
@@ -0,0 +1,260 @@
+package exec
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+	"weak"
+)
+
+// commandsCreated counts commands created by this package.
+var commandsCreated atomic.Uint64
+
+// Failure counters reported by GetStats.
+var (
+	startFailures    atomic.Uint64
+	extractFailures  atomic.Uint64
+	watchdogFailures atomic.Uint64
+)
+
+// registry tracks every command created by this package that is still referenced.
+var registry = struct {
+	sync.Mutex
+	entries map[weak.Pointer[Cmd]]*registryEntry
+	added   int
+}{entries: map[weak.Pointer[Cmd]]*registryEntry{}}
+
+type registryEntry struct {
+	grace time.Duration
+	// signalled is set once this package has deliberately signalled the command.
+	signalled bool
+	// pid of the intermediary once the command has started, or 0.
+	pid int
+	// exited is set once the command has been waited for.
+	exited bool
+	// timeout is set by SetTimeout, and timedOut once it has expired.
+	timeout  time.Duration
+	timedOut bool
+}
+
+func register(cmd *Cmd) {
+	commandsCreated.Add(1)
+	registry.Lock()
+	defer registry.Unlock()
+	registry.entries[weak.Make(cmd)] = &registryEntry{}
+	registry.added++
+	if registry.added%256 == 0 {
+		for ptr := range registry.entries {
+			if ptr.Value() == nil {
+				delete(registry.entries, ptr)
+			}
+		}
+	}
+}
+
+// SetGracePeriod sets how long ShutdownAll waits for cmd to exit after SIGTERM before sending SIGKILL. It is also the
+// time a Pipeline stage is given to drain its input at each step of the pipeline's teardown.
+//
+// The grace period is additionally bounded by the context passed to ShutdownAll.
+func SetGracePeriod(cmd *Cmd, grace time.Duration) {
+	registry.Lock()
+	defer registry.Unlock()
+	if entry, ok := registry.entries[weak.Make(cmd)]; ok {
+		entry.grace = grace
+	}
+}
+
+// SetTimeout bounds how long cmd may run once started. When the timeout expires, cmd's process group is sent SIGTERM,
+// then SIGKILL once its grace period (see SetGracePeriod) has elapsed, and Wait reports the timeout.
+//
+// Unlike a context deadline, which kills the command at once, this gives it a chance to exit cleanly, eg. so that it
+// flushes its output to the next stage of a Pipeline.
+func SetTimeout(cmd *Cmd, timeout time.Duration) {
+	registry.Lock()
+	defer registry.Unlock()
+	if entry, ok := registry.entries[weak.Make(cmd)]; ok {
+		entry.timeout = timeout
+	}
+}
+
+// startTimeout arms the timeout set for cmd by SetTimeout, once it has started with its intermediary running as pid.
+func startTimeout(cmd *Cmd, pid int) {
+	registry.Lock()
+	entry, ok := registry.entries[weak.Make(cmd)]
+	var timeout time.Duration
+	if ok {
+		timeout = entry.timeout
+	}
+	registry.Unlock()
+	if timeout <= 0 {
+		return
+	}
+	timer := time.AfterFunc(timeout, func() {
+		registry.Lock()
+		entry.timedOut = true
+		entry.signalled = true
+		grace := entry.grace
+		registry.Unlock()
+		if signalGroup(pid, syscall.SIGTERM) != nil {
+			return
+		}
+		deadline := time.Now().Add(grace)
+		for groupAlive(pid) {
+			if time.Now().After(deadline) {
+				_ = signalGroup(pid, syscall.SIGKILL)
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	})
+	cmd.cleanups = append(cmd.cleanups, func() { timer.Stop() })
+}
+
+// timedOut returns the timeout set for cmd by SetTimeout, if it has expired.
+func timedOut(cmd *Cmd) (time.Duration, bool) {
+	registry.Lock()
+	defer registry.Unlock()
+	entry, ok := registry.entries[weak.Make(cmd)]
+	if !ok || !entry.timedOut {
+		return 0, false
+	}
+	return entry.timeout, true
+}
+
+// gracePeriod returns the grace period set for cmd by SetGracePeriod, or 0.
+func gracePeriod(cmd *Cmd) time.Duration {
+	registry.Lock()
+	defer registry.Unlock()
+	if entry, ok := registry.entries[weak.Make(cmd)]; ok {
+		return entry.grace
+	}
+	return 0
+}
+
+// markSignalled records that the package is about to signal cmd, so that its termination is not mistaken for the
+// intermediary dying unexpectedly.
+func markSignalled(cmd *Cmd) {
+	registry.Lock()
+	defer registry.Unlock()
+	if entry, ok := registry.entries[weak.Make(cmd)]; ok {
+		entry.signalled = true
+	}
+}
+
+// markStarted records that cmd has started, with its intermediary running as pid.
+//
+// The registry keeps its own copy of this state because os/exec sets Process and ProcessState without
+// synchronization, so they cannot be read while another goroutine may be in Start or Wait.
+func markStarted(cmd *Cmd, pid int) {
+	registry.Lock()
+	defer registry.Unlock()
+	if entry, ok := registry.entries[weak.Make(cmd)]; ok {
+		entry.pid = pid
+	}
+}
+
+// markExited records that cmd has been waited for.
+func markExited(cmd *Cmd) {
+	registry.Lock()
+	defer registry.Unlock()
+	if entry, ok := registry.entries[weak.Make(cmd)]; ok {
+		entry.exited = true
+	}
+}
+
+// wasSignalled reports whether markSignalled has been called for cmd.
+func wasSignalled(cmd *Cmd) bool {
+	registry.Lock()
+	defer registry.Unlock()
+	entry, ok := registry.entries[weak.Make(cmd)]
+	return ok && entry.signalled
+}
+
+type runningCmd struct {
+	cmd   *Cmd
+	pid   int
+	grace time.Duration
+}
+
+// running returns all tracked commands that have been started and not yet waited for.
+func running() []runningCmd {
+	var out []runningCmd
+	registry.Lock()
+	defer registry.Unlock()
+	for ptr, entry := range registry.entries {
+		cmd := ptr.Value()
+		if cmd == nil {
+			delete(registry.entries, ptr)
+			continue
+		}
+		if entry.pid != 0 && !entry.exited && groupAlive(entry.pid) {
+			out = append(out, runningCmd{cmd, entry.pid, entry.grace})
+		}
+	}
+	return out
+}
+
+// ShutdownResult is the outcome of terminating a single command in ShutdownAll.
+type ShutdownResult struct {
+	Cmd *Cmd
+	// Killed is true if the command did not exit within its grace period and was sent SIGKILL.
+	Killed bool
+	// Err is any error encountered signalling the command.
+	Err error
+}
+
+// ShutdownAll gracefully terminates every running command created by this package.
+//
+// Each command's process group is sent SIGTERM, then SIGKILL if it has not exited once its grace period (see
+// SetGracePeriod) has elapsed or ctx is done, whichever is first. A command without a grace period is only killed
+// when ctx is done. ShutdownAll returns once every command has exited or been killed.
+//
+// A command has exited once every process in its group has exited and the intermediary has been waited for, so
+// commands that nothing is waiting on are always reported as killed. Commands are tracked only while the caller holds
+// a reference to their *Cmd, and only if they are started through the *Cmd rather than the os/exec.Cmd returned by
+// Unwrap.
+func ShutdownAll(ctx context.Context) []ShutdownResult {
+	targets := running()
+	results := make([]ShutdownResult, len(targets))
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Go(func() {
+			result := ShutdownResult{Cmd: target.cmd}
+			pid := target.pid
+			markSignalled(target.cmd)
+			if err := signalGroup(pid, syscall.SIGTERM); err != nil {
+				result.Err = err
+				results[i] = result
+				return
+			}
+			ctx := ctx
+			if target.grace > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, target.grace)
+				defer cancel()
+			}
+			ticker := time.NewTicker(10 * time.Millisecond)
+			defer ticker.Stop()
+			for groupAlive(pid) {
+				select {
+				case <-ctx.Done():
+					result.Killed = true
+					result.Err = signalGroup(pid, syscall.SIGKILL)
+					results[i] = result
+					return
+				case <-ticker.C:
+				}
+			}
+			results[i] = result
+		})
+	}
+	wg.Wait()
+	return results
+}
+
+// groupAlive reports whether any process remains in the process group led by pid.
+func groupAlive(pid int) bool {
+	return signalGroup(pid, 0) == nil
+}
@@ -0,0 +1,63 @@
+//go:build (linux || darwin) && (amd64 || arm64)
+
+package exec_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/exec"
+)
+
+func TestShutdownAll(t *testing.T) {
+	graceful := exec.Command("sleep", "10")
+	stubborn := exec.Command("sh", "-c", `trap "" TERM; while :; do sleep 0.05; done`)
+	exec.SetGracePeriod(stubborn, 200*time.Millisecond)
+
+	done := make(chan error, 2)
+	for _, cmd := range []*exec.Cmd{graceful, stubborn} {
+		if err := cmd.Start(); err != nil {
+			t.Fatalf("Failed to start: %v", err)
+		}
+		go func() { done <- cmd.Wait() }()
+	}
+	// Give the shell time to install its trap.
+	time.Sleep(200 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	results := exec.ShutdownAll(ctx)
+
+	killed := map[*exec.Cmd]bool{}
+	for _, result := range results {
+		if result.Err != nil {
+			t.Errorf("Unexpected error shutting down %v: %v", result.Cmd, result.Err)
+		}
+		killed[result.Cmd] = result.Killed
+	}
+	if k, ok := killed[graceful]; !ok || k {
+		t.Errorf("Expected graceful command to exit on SIGTERM, results: %v", results)
+	}
+	if k, ok := killed[stubborn]; !ok || !k {
+		t.Errorf("Expected stubborn command to be killed, results: %v", results)
+	}
+	for range 2 {
+		<-done
+	}
+}
+
+func TestSetTimeout(t *testing.T) {
+	cmd := exec.Command("sh", "-c", `trap 'echo terminated; exit 0' TERM; sleep 10 & wait`)
+	exec.SetTimeout(cmd, 100*time.Millisecond)
+	exec.SetGracePeriod(cmd, 5*time.Second)
+	output, err := cmd.Output()
+	if err == nil || !strings.Contains(err.Error(), "timed out after 100ms") {
+		t.Errorf("Expected a timeout, got %v", err)
+	}
+	// The command must have been sent SIGTERM and allowed to exit cleanly, rather than killed.
+	if string(output) != "terminated\n" {
+		t.Errorf("Expected %q, got %q", "terminated\n", output)
+	}
+}
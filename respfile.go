@@ -0,0 +1,60 @@
+//go:build (linux || darwin) && (amd64 || arm64)
+
+package exec
+
+import (
+	"os"
+	"strings"
+	"unsafe"
+)
+
+// ResponseFile moves arguments that would exceed ArgMax into a temporary response file, passed to the command as a
+// single argument of argPrefix followed by the file's path (eg. "@" for GCC, Clang, javac and MSVC).
+//
+// Leading arguments that fit are left in place and the response file replaces the remainder, preserving order.
+// Arguments in the file are double quoted with backslash escapes, as understood by GCC-style tools. If all arguments
+// fit, the command is unchanged. Call the returned cleanup function after the command exits to remove the file.
+func ResponseFile(cmd *Cmd, argPrefix string) (cleanup func() error, err error) {
+	cleanup = func() error { return nil }
+	if ArgSize(cmd) <= ArgMax()-chunkHeadroom {
+		return cleanup, nil
+	}
+	args := cmd.Args
+	pointer := int(unsafe.Sizeof(uintptr(0)))
+	// Reserve room for the response file argument itself.
+	budget := ArgMax() - chunkHeadroom - ArgSize(cmd) - (len(argPrefix) + 4096 + pointer)
+	for _, arg := range args[1:] {
+		budget += len(arg) + 1 + pointer
+	}
+	keep := 1
+	for keep < len(args) {
+		size := len(args[keep]) + 1 + pointer
+		if size > budget {
+			break
+		}
+		budget -= size
+		keep++
+	}
+
+	w, err := os.CreateTemp("", "go-exec-response-*")
+	if err != nil {
+		return nil, err
+	}
+	for _, arg := range args[keep:] {
+		if _, err := w.WriteString(quoteResponseArg(arg) + "\n"); err != nil {
+			_ = w.Close()
+			_ = os.Remove(w.Name())
+			return nil, err
+		}
+	}
+	if err := w.Close(); err != nil {
+		_ = os.Remove(w.Name())
+		return nil, err
+	}
+	cmd.Args = append(append([]string(nil), args[:keep]...), argPrefix+w.Name())
+	return func() error { return os.Remove(w.Name()) }, nil
+}
+
+func quoteResponseArg(arg string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(arg) + `"`
+}
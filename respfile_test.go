@@ -0,0 +1,63 @@
+//go:build (linux || darwin) && (amd64 || arm64)
+
+package exec_test
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/exec"
+)
+
+func TestResponseFile(t *testing.T) {
+	cmd := exec.Command("echo", "small")
+	cleanup, err := exec.ResponseFile(cmd, "@")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cleanup(); err != nil {
+		t.Fatal(err)
+	}
+	if len(cmd.Args) != 2 || cmd.Args[0] != "echo" || cmd.Args[1] != "small" {
+		t.Errorf("Expected small command to be unchanged, got %v", cmd.Args)
+	}
+
+	items := make([]string, exec.ArgMax()/500)
+	for i := range items {
+		items[i] = fmt.Sprintf(`"quoted\%0500d`, i)
+	}
+	cmd = exec.Command("cc", items...)
+	cleanup, err = exec.ResponseFile(cmd, "@")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := exec.CheckArgs(cmd); err != nil {
+		t.Errorf("Expected arguments to fit after moving to a response file: %v", err)
+	}
+	last := cmd.Args[len(cmd.Args)-1]
+	path, ok := strings.CutPrefix(last, "@")
+	if !ok {
+		t.Fatalf("Expected response file argument, got %q", last)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	kept := len(cmd.Args) - 2 // cc, and the response file
+	if kept+len(lines) != len(items) {
+		t.Errorf("Expected %d arguments in total, got %d kept and %d in the response file", len(items), kept, len(lines))
+	}
+	if expected := `"\"quoted\\` + items[len(items)-1][len(`"quoted\`):] + `"`; lines[len(lines)-1] != expected {
+		t.Errorf("Expected last line %q, got %q", expected, lines[len(lines)-1])
+	}
+
+	if err := cleanup(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected response file to be removed, got %v", err)
+	}
+}
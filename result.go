@@ -0,0 +1,105 @@
+package exec
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// maxSummaryLines is the number of lines of stderr included in a Result's summary.
+const maxSummaryLines = 5
+
+// Result records the outcome of a command run with RunResult.
+type Result struct {
+	// Command line, as rendered by Cmd.ShellString.
+	Command  string
+	Started  time.Time
+	Duration time.Duration
+	// ExitCode of the command, or -1 if it did not exit normally.
+	ExitCode int
+	Err      error
+	// Stderr is everything the command wrote to stderr.
+	Stderr []byte
+	// Profile is the path of the profile recorded by the command, if it was run with Profile and one was written.
+	Profile string
+}
+
+// RunResult runs cmd and returns a Result describing it. Stderr is captured in addition to being written to
+// cmd.Stderr, if set.
+func RunResult(cmd *Cmd) *Result {
+	var stderr bytes.Buffer
+	if cmd.Stderr == nil {
+		cmd.Stderr = &stderr
+	} else {
+		cmd.Stderr = io.MultiWriter(cmd.Stderr, &stderr)
+	}
+	result := &Result{Command: cmd.ShellString(), Started: time.Now()}
+	result.Err = cmd.Run()
+	result.Duration = time.Since(result.Started)
+	result.Stderr = stderr.Bytes()
+	result.ExitCode = -1
+	if cmd.ProcessState != nil {
+		result.ExitCode = cmd.ProcessState.ExitCode()
+	}
+	if cmd.profile != "" {
+		if _, err := os.Stat(cmd.profile); err == nil {
+			result.Profile = cmd.profile
+		}
+	}
+	return result
+}
+
+// ErrorLines returns up to five lines of stderr that best explain a failure: the lines that look like errors, or
+// failing that, the last lines written.
+func (r *Result) ErrorLines() []string {
+	lines := strings.Split(strings.TrimRight(string(r.Stderr), "\n"), "\n")
+	if len(lines) == 1 && lines[0] == "" {
+		return nil
+	}
+	var errorLines []string
+	for _, line := range lines {
+		if DefaultLevelPatterns[0].Pattern.MatchString(line) {
+			errorLines = append(errorLines, line)
+		}
+	}
+	if len(errorLines) == 0 {
+		errorLines = lines[max(0, len(lines)-maxSummaryLines):]
+	}
+	return errorLines[:min(len(errorLines), maxSummaryLines)]
+}
+
+func (r *Result) status() string {
+	switch {
+	case r.Err == nil:
+		return "succeeded"
+	case r.ExitCode >= 0:
+		return fmt.Sprintf("failed with exit code %d", r.ExitCode)
+	default:
+		return "failed: " + r.Err.Error()
+	}
+}
+
+// Summary returns a compact, human-readable description of the result.
+func (r *Result) Summary() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "$ %s\n%s after %s\n", r.Command, r.status(), r.Duration.Round(time.Millisecond))
+	if r.Err != nil {
+		for _, line := range r.ErrorLines() {
+			fmt.Fprintf(&b, "  %s\n", line)
+		}
+	}
+	return b.String()
+}
+
+// Markdown returns the result formatted as Markdown, suitable for CI annotations and job summaries.
+func (r *Result) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "`%s` %s after %s\n", strings.ReplaceAll(r.Command, "`", "'"), r.status(), r.Duration.Round(time.Millisecond))
+	if lines := r.ErrorLines(); r.Err != nil && len(lines) > 0 {
+		fmt.Fprintf(&b, "\n```\n%s\n```\n", strings.Join(lines, "\n"))
+	}
+	return b.String()
+}
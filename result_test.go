@@ -0,0 +1,38 @@
+//go:build (linux || darwin) && (amd64 || arm64)
+
+package exec_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/exec"
+)
+
+func TestResultSummary(t *testing.T) {
+	shPath, err := exec.LookPath("sh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	truePath, err := exec.LookPath("true")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := exec.RunResult(exec.Command("sh", "-c", `echo "compiling" >&2; echo "main.c:3: error: expected ';'" >&2; exit 2`))
+	if result.ExitCode != 2 || result.Err == nil {
+		t.Fatalf("Expected exit code 2, got %d (%v)", result.ExitCode, result.Err)
+	}
+	summary := result.Summary()
+	if !strings.HasPrefix(summary, "$ "+shPath+" -c ") || !strings.Contains(summary, "\nfailed with exit code 2 after ") || !strings.HasSuffix(summary, "\n  main.c:3: error: expected ';'\n") {
+		t.Errorf("Unexpected summary:\n%s", summary)
+	}
+	markdown := result.Markdown()
+	if !strings.HasSuffix(markdown, "\n\n```\nmain.c:3: error: expected ';'\n```\n") {
+		t.Errorf("Unexpected Markdown:\n%s", markdown)
+	}
+
+	result = exec.RunResult(exec.Command("true"))
+	if summary := result.Summary(); !strings.HasPrefix(summary, "$ "+truePath+"\nsucceeded after ") {
+		t.Errorf("Unexpected summary:\n%s", summary)
+	}
+}
@@ -0,0 +1,120 @@
+//go:build (linux || darwin) && (amd64 || arm64)
+
+package exec
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// SelfTestCheck is the result of a single self-test check.
+type SelfTestCheck struct {
+	Name string
+	Err  error
+}
+
+// SelfTestReport describes the environment and whether the package works in it.
+type SelfTestReport struct {
+	// Target is the intermediary target for this platform, eg. "x86_64-linux".
+	Target string
+	// Kernel is the kernel release.
+	Kernel string
+	// Sandbox is the name of a detected sandboxing runtime (eg. "gVisor"), if any.
+	Sandbox string
+	// Seccomp is true if the process is running under a seccomp filter.
+	Seccomp bool
+	// ParentDeathLatency is the measured time between the parent being killed and its child terminating.
+	ParentDeathLatency time.Duration
+	Checks             []SelfTestCheck
+}
+
+// Err returns an error combining all failed checks, or nil.
+func (r *SelfTestReport) Err() error {
+	var errs []error
+	for _, check := range r.Checks {
+		if check.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", check.Name, check.Err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SelfTest verifies that the intermediary can be extracted, can run commands, and terminates its child when its
+// parent dies, and reports details of the environment that commonly affect this.
+//
+// The embedded Linux intermediaries are statically linked against musl, so they do not depend on the host libc.
+// Failures are most commonly caused by noexec temporary directories, seccomp filters that block fork or kill, or
+// sandboxed kernels.
+func SelfTest() *SelfTestReport {
+	report := &SelfTestReport{Target: targetMap[runtime.GOARCH+"-"+runtime.GOOS]}
+	report.Kernel, report.Sandbox, report.Seccomp = detectEnvironment()
+	check := func(name string, fn func() error) bool {
+		err := fn()
+		report.Checks = append(report.Checks, SelfTestCheck{Name: name, Err: err})
+		return err == nil
+	}
+	var path string
+	if !check("extract", func() (err error) {
+		path, err = extract()
+		return err
+	}) {
+		return report
+	}
+	if !check("spawn", func() error { return Command("sh", "-c", "exit 0").Run() }) {
+		return report
+	}
+	check("parent-death", func() (err error) {
+		report.ParentDeathLatency, err = selfTestParentDeath(path)
+		return err
+	})
+	return report
+}
+
+// selfTestParentDeath runs the intermediary under a shell, kills the shell, and measures how long it takes for the
+// child to be terminated.
+//
+// Note that the intermediary treats an unreaped parent as alive, so latency can be much higher than the poll
+// interval where orphaned zombies are reaped slowly, such as in containers without an init process.
+func selfTestParentDeath(intermediary string) (time.Duration, error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close() //nolint
+	parent := exec.Command("sh", "-c", `"$0" sh -c 'echo $$; exec sleep 60' & wait`, intermediary)
+	parent.Stdout = w
+	err = parent.Start()
+	w.Close() //nolint
+	if err != nil {
+		return 0, err
+	}
+	line, err := bufio.NewReader(r).ReadString('\n')
+	_ = parent.Process.Kill()
+	_ = parent.Wait()
+	killed := time.Now()
+	if err != nil {
+		return 0, fmt.Errorf("child did not start: %w", err)
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(line))
+	if err != nil {
+		return 0, err
+	}
+
+	deadline := killed.Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if !processAlive(pid) {
+			return time.Since(killed), nil
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	_ = syscall.Kill(pid, syscall.SIGKILL)
+	return 0, fmt.Errorf("child %d survived the death of its parent", pid)
+}
@@ -0,0 +1,23 @@
+//go:build amd64 || arm64
+
+package exec
+
+import "syscall"
+
+func detectEnvironment() (kernel, sandbox string, seccomp bool) {
+	kernel, _ = syscall.Sysctl("kern.osrelease")
+	return
+}
+
+// processAlive reports whether pid is running.
+func processAlive(pid int) bool {
+	return syscall.Kill(pid, 0) == nil
+}
+
+// selfEmulator returns EmulatorRosetta if this process is being translated by Rosetta, or "".
+func selfEmulator() string {
+	if translated, err := syscall.SysctlUint32("sysctl.proc_translated"); err == nil && translated == 1 {
+		return EmulatorRosetta
+	}
+	return ""
+}
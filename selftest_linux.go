@@ -0,0 +1,61 @@
+//go:build amd64 || arm64
+
+package exec
+
+import (
+	"bytes"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+func detectEnvironment() (kernel, sandbox string, seccomp bool) {
+	var uts syscall.Utsname
+	if err := syscall.Uname(&uts); err == nil {
+		kernel = utsString(uts.Release[:])
+	}
+	// gVisor reports a fixed, fictitious kernel version.
+	if version, err := os.ReadFile("/proc/version"); err == nil && bytes.Contains(version, []byte("#1 SMP Sun Jan 10 15:06:54 PST 2016")) {
+		sandbox = "gVisor"
+	}
+	if status, err := os.ReadFile("/proc/self/status"); err == nil {
+		for line := range strings.Lines(string(status)) {
+			if value, ok := strings.CutPrefix(line, "Seccomp:"); ok {
+				seccomp = strings.TrimSpace(value) == "2"
+			}
+		}
+	}
+	return
+}
+
+func utsString[T int8 | uint8](field []T) string {
+	out := make([]byte, 0, len(field))
+	for _, c := range field {
+		if c == 0 {
+			break
+		}
+		out = append(out, byte(c))
+	}
+	return string(out)
+}
+
+// processAlive reports whether pid is running. Zombies are considered dead.
+func processAlive(pid int) bool {
+	stat, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return false
+	}
+	// The state follows the parenthesised command name, which may itself contain parentheses.
+	_, rest, ok := bytes.Cut(stat[bytes.LastIndexByte(stat, ')')+1:], []byte(" "))
+	return ok && len(rest) > 0 && rest[0] != 'Z'
+}
+
+// selfEmulator returns the binfmt_misc interpreter running this process, such as qemu-user, or "".
+func selfEmulator() string {
+	self, err := os.Executable()
+	if err != nil {
+		return ""
+	}
+	return binfmtInterpreter(self)
+}
@@ -0,0 +1,20 @@
+//go:build (linux || darwin) && (amd64 || arm64)
+
+package exec_test
+
+import (
+	"testing"
+
+	"github.com/alecthomas/exec"
+)
+
+func TestSelfTest(t *testing.T) {
+	report := exec.SelfTest()
+	if err := report.Err(); err != nil {
+		t.Fatalf("SelfTest failed: %v", err)
+	}
+	if len(report.Checks) != 3 {
+		t.Errorf("Expected 3 checks, got %v", report.Checks)
+	}
+	t.Logf("%+v", report)
+}
@@ -0,0 +1,181 @@
+package exec
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// inflight tracks SharedOutput calls that are running, by key.
+var inflight = struct {
+	sync.Mutex
+	calls map[string]*sharedCall
+}{calls: map[string]*sharedCall{}}
+
+type sharedCall struct {
+	done   chan struct{}
+	output []byte
+	stderr []byte
+	err    error
+}
+
+// SharedOutput is like cmd.Output, except that concurrent calls for identical commands share a single child process
+// and its result, so that eg. many goroutines asking for "git rev-parse HEAD" run it once.
+//
+// Commands are identical if they resolve to the same executable and have the same arguments, working directory,
+// environment and stdin. Only the first of the identical commands is run; the others are never started. Each caller
+// receives its own copy of the output, and the shared command's stderr is written to the Stderr of each caller that
+// set one. Both are held in memory until the command exits.
+//
+// Stdin can only be compared if it is nil, a *bytes.Reader, a *strings.Reader or a *bytes.Buffer, and is compared
+// without being consumed. A command with any other stdin, eg. a pipe, is never shared and simply runs cmd.Output, as
+// is a command with ExtraFiles or with process attributes other than those every command is given.
+func SharedOutput(cmd *Cmd) ([]byte, error) {
+	if cmd.Err != nil {
+		return nil, cmd.Err
+	}
+	if !shareable(cmd) {
+		return cmd.Output()
+	}
+	key, ok, err := sharedKey(cmd)
+	if err != nil {
+		cmd.cleanup()
+		return nil, err
+	}
+	if !ok {
+		return cmd.Output()
+	}
+	inflight.Lock()
+	if call, ok := inflight.calls[key]; ok {
+		inflight.Unlock()
+		<-call.done
+		// The follower is never started, so it is finished with once the result is in.
+		cmd.cleanup()
+		if cmd.Stderr != nil {
+			_, _ = cmd.Stderr.Write(call.stderr)
+		}
+		return bytes.Clone(call.output), call.err
+	}
+	call := &sharedCall{done: make(chan struct{})}
+	inflight.calls[key] = call
+	inflight.Unlock()
+
+	// Followers must be released even if the command panics, eg. in one of its writers.
+	finished := false
+	defer func() {
+		if !finished {
+			call.output, call.err = nil, errors.New("exec: shared command panicked")
+		}
+		inflight.Lock()
+		delete(inflight.calls, key)
+		inflight.Unlock()
+		close(call.done)
+	}()
+	call.output, call.stderr, call.err = runShared(cmd)
+	finished = true
+	return bytes.Clone(call.output), call.err
+}
+
+// runShared runs cmd for SharedOutput, recording its stderr for the callers sharing it as well as writing it to the
+// command's own Stderr.
+func runShared(cmd *Cmd) ([]byte, []byte, error) {
+	var stderr bytes.Buffer
+	own := cmd.Stderr
+	if own == nil {
+		cmd.Stderr = &stderr
+	} else {
+		cmd.Stderr = io.MultiWriter(own, &stderr)
+	}
+	output, err := cmd.Output()
+	cmd.Stderr = own
+	// Output only records stderr in the *ExitError if it set Stderr itself.
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		exitErr.Stderr = stderr.Bytes()[:min(stderr.Len(), maxOutputStderr)]
+	}
+	return output, stderr.Bytes(), err
+}
+
+// shareable reports whether cmd has no process attributes that sharedKey does not compare: extra files, or process
+// attributes other than those every command is given.
+func shareable(cmd *Cmd) bool {
+	if len(cmd.ExtraFiles) > 0 {
+		return false
+	}
+	expected := &Cmd{Cmd: &exec.Cmd{}, direct: cmd.direct}
+	setpgid(expected)
+	return reflect.DeepEqual(cmd.SysProcAttr, expected.SysProcAttr)
+}
+
+// sharedKey identifies the command for SharedOutput. It returns false if the command's stdin can't be compared.
+//
+// Each field is length-prefixed before hashing, so that no choice of arguments can make two commands collide.
+func sharedKey(cmd *Cmd) (string, bool, error) {
+	stdin, ok := peekStdin(cmd.Stdin)
+	if !ok {
+		return "", false, nil
+	}
+	dir := cmd.Dir
+	if dir == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return "", false, err
+		}
+		dir = wd
+	}
+	args := cmd.Args
+	exe, err := cmd.resolveBinary(dir)
+	if err != nil {
+		return "", false, err
+	}
+	env := cmd.Env
+	if env == nil {
+		env = os.Environ()
+	}
+	h := sha256.New()
+	field := func(value string) {
+		h.Write(binary.AppendUvarint(nil, uint64(len(value)))) //nolint
+		h.Write([]byte(value))                                 //nolint
+	}
+	field(exe)
+	field(strconv.Itoa(len(args)))
+	for _, arg := range args {
+		field(arg)
+	}
+	field(dir)
+	field(envDigest(env))
+	field(string(stdin))
+	return formatDigest(h), true, nil
+}
+
+// peekStdin returns the unread content of stdin without consuming it, or false if stdin may be a stream.
+func peekStdin(stdin io.Reader) ([]byte, bool) {
+	switch r := stdin.(type) {
+	case nil:
+		return nil, true
+	case *bytes.Buffer:
+		return r.Bytes(), true
+	case *bytes.Reader:
+		return peekReaderAt(r, r.Size(), r.Len())
+	case *strings.Reader:
+		return peekReaderAt(r, r.Size(), r.Len())
+	default:
+		return nil, false
+	}
+}
+
+func peekReaderAt(r io.ReaderAt, size int64, unread int) ([]byte, bool) {
+	data := make([]byte, unread)
+	if _, err := r.ReadAt(data, size-int64(unread)); err != nil && err != io.EOF {
+		return nil, false
+	}
+	return data, true
+}
@@ -0,0 +1,151 @@
+//go:build (linux || darwin) && (amd64 || arm64)
+
+package exec_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	stdexec "os/exec"
+
+	"github.com/alecthomas/exec"
+)
+
+func TestSharedOutput(t *testing.T) {
+	counter := filepath.Join(t.TempDir(), "counter")
+	script := `echo run >> "$0"; sleep 0.3; echo shared`
+	var wg sync.WaitGroup
+	outputs := make([]string, 5)
+	for i := range outputs {
+		wg.Go(func() {
+			output, err := exec.SharedOutput(exec.Command("sh", "-c", script, counter))
+			if err != nil {
+				t.Error(err)
+			}
+			outputs[i] = string(output)
+		})
+	}
+	wg.Wait()
+	for _, output := range outputs {
+		if output != "shared\n" {
+			t.Errorf("Expected %q, got %q", "shared\n", output)
+		}
+	}
+	runs, err := os.ReadFile(counter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count := strings.Count(string(runs), "run"); count != 1 {
+		t.Errorf("Expected 1 run, got %d", count)
+	}
+
+	// Different stdin must not be shared.
+	a := exec.Command("cat")
+	a.Stdin = strings.NewReader("a")
+	b := exec.Command("cat")
+	b.Stdin = strings.NewReader("b")
+	outA, _ := exec.SharedOutput(a)
+	outB, _ := exec.SharedOutput(b)
+	if string(outA) != "a" || string(outB) != "b" {
+		t.Errorf("Expected distinct outputs, got %q and %q", outA, outB)
+	}
+}
+
+func TestSharedOutputUnshared(t *testing.T) {
+	script := `echo run >> "$0"; sleep 0.3; cat`
+	run := func(t *testing.T, commands ...*exec.Cmd) int {
+		t.Helper()
+		counter := filepath.Join(t.TempDir(), "counter")
+		var wg sync.WaitGroup
+		for _, cmd := range commands {
+			cmd.Args = append(cmd.Args[:3], append([]string{counter}, cmd.Args[3:]...)...)
+			wg.Go(func() {
+				if _, err := exec.SharedOutput(cmd); err != nil {
+					t.Error(err)
+				}
+			})
+		}
+		wg.Wait()
+		runs, err := os.ReadFile(counter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.Count(string(runs), "run")
+	}
+
+	t.Run("PipeStdin", func(t *testing.T) {
+		commands := make([]*exec.Cmd, 2)
+		for i := range commands {
+			r, w, err := os.Pipe()
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { r.Close() }) //nolint
+			go func() {
+				w.WriteString("same") //nolint
+				w.Close()             //nolint
+			}()
+			commands[i] = exec.Command("sh", "-c", script)
+			commands[i].Stdin = r
+		}
+		if count := run(t, commands...); count != 2 {
+			t.Errorf("Expected 2 runs, got %d", count)
+		}
+	})
+
+	t.Run("ExtraFiles", func(t *testing.T) {
+		f, err := os.Open(os.DevNull)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close() //nolint
+		commands := []*exec.Cmd{exec.Command("sh", "-c", script), exec.Command("sh", "-c", script)}
+		for _, cmd := range commands {
+			cmd.ExtraFiles = []*os.File{f}
+		}
+		if count := run(t, commands...); count != 2 {
+			t.Errorf("Expected 2 runs, got %d", count)
+		}
+	})
+
+	t.Run("SeparatorsInArgs", func(t *testing.T) {
+		a := exec.Command("sh", "-c", script, "a\x01", "b")
+		b := exec.Command("sh", "-c", script, "a", "\x01b")
+		if count := run(t, a, b); count != 2 {
+			t.Errorf("Expected 2 runs, got %d", count)
+		}
+	})
+}
+
+func TestSharedOutputStderr(t *testing.T) {
+	script := `sleep 0.3; echo oops >&2; exit 3`
+	var wg sync.WaitGroup
+	stderrs := make([]bytes.Buffer, 3)
+	errs := make([]error, 4)
+	for i := range errs {
+		wg.Go(func() {
+			cmd := exec.Command("sh", "-c", script)
+			if i < len(stderrs) {
+				cmd.Stderr = &stderrs[i]
+			}
+			_, errs[i] = exec.SharedOutput(cmd)
+		})
+	}
+	wg.Wait()
+	for i := range stderrs {
+		if stderrs[i].String() != "oops\n" {
+			t.Errorf("Expected %q, got %q", "oops\n", stderrs[i].String())
+		}
+	}
+	for _, err := range errs {
+		var exitErr *stdexec.ExitError
+		if !errors.As(err, &exitErr) || string(exitErr.Stderr) != "oops\n" {
+			t.Errorf("Expected an exit error recording stderr, got %v", err)
+		}
+	}
+}
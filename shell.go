@@ -0,0 +1,70 @@
+package exec
+
+import (
+	"strings"
+)
+
+// ShellString renders the command as a correctly quoted POSIX shell command line that can be pasted into a shell.
+//
+// Unlike String(), it includes the working directory and any environment changes relative to the current process (see
+// EnvDiff), so that the pasted command runs the same way.
+func (c *Cmd) ShellString() string {
+	var parts []string
+	if c.Dir != "" {
+		parts = append(parts, "cd", ShellQuote(c.Dir), "&&")
+	}
+	var assignments []string
+	removed := false
+	for _, change := range EnvDiff(c) {
+		if change.Kind == EnvRemoved {
+			if !removed {
+				parts = append(parts, "env")
+				removed = true
+			}
+			parts = append(parts, "-u", ShellQuote(change.Name))
+			continue
+		}
+		assignments = append(assignments, change.Name+"="+ShellQuote(change.New))
+	}
+	parts = append(parts, assignments...)
+	// A program containing '=' would be taken as another assignment: by the shell unless it is quoted, and by env
+	// regardless, so under env it is run through sh instead.
+	program := c.Path
+	if strings.Contains(program, "=") {
+		if removed {
+			parts = append(parts, "sh", "-c", `'exec "$0" "$@"'`)
+		}
+		parts = append(parts, singleQuote(program))
+	} else {
+		parts = append(parts, ShellQuote(program))
+	}
+	if len(c.Args) > 1 {
+		for _, arg := range c.Args[1:] {
+			parts = append(parts, ShellQuote(arg))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// ShellQuote quotes s for safe use as a single word in a POSIX shell.
+func ShellQuote(s string) string {
+	if s == "" {
+		return "''"
+	}
+	if strings.IndexFunc(s, isShellUnsafe) == -1 {
+		return s
+	}
+	return singleQuote(s)
+}
+
+func singleQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func isShellUnsafe(r rune) bool {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		return false
+	}
+	return !strings.ContainsRune("_@%+=:,./-", r)
+}
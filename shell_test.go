@@ -0,0 +1,93 @@
+//go:build (linux || darwin) && (amd64 || arm64)
+
+package exec_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	stdexec "os/exec"
+
+	"github.com/alecthomas/exec"
+)
+
+func TestShellQuote(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"", "''"},
+		{"simple", "simple"},
+		{"path/to-file.txt", "path/to-file.txt"},
+		{"with space", "'with space'"},
+		{"it's", `'it'\''s'`},
+		{"$HOME", "'$HOME'"},
+		{"a\nb", "'a\nb'"},
+	}
+	for _, tt := range tests {
+		if actual := exec.ShellQuote(tt.input); actual != tt.expected {
+			t.Errorf("ShellQuote(%q): expected %q, got %q", tt.input, tt.expected, actual)
+		}
+	}
+}
+
+func TestShellStringRoundTrip(t *testing.T) {
+	args := []string{"echo", "with space", "it's", "$HOME", "`backtick`", ""}
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Dir = t.TempDir()
+	cmd.Env = append(os.Environ(), "EXEC_TEST_VAR=a b")
+
+	line := cmd.ShellString()
+	if !strings.HasPrefix(line, "cd ") {
+		t.Errorf("Expected working directory in %q", line)
+	}
+	if !strings.Contains(line, "EXEC_TEST_VAR='a b'") {
+		t.Errorf("Expected environment assignment in %q", line)
+	}
+
+	output, err := stdexec.Command("sh", "-c", line).Output()
+	if err != nil {
+		t.Fatalf("Failed to run %q: %v", line, err)
+	}
+	expected := strings.Join(args[1:], " ") + "\n"
+	if string(output) != expected {
+		t.Errorf("Expected %q, got %q", expected, string(output))
+	}
+}
+
+func TestShellStringProgramWithEquals(t *testing.T) {
+	dir := t.TempDir()
+	program := filepath.Join(dir, "a=b")
+	if err := os.WriteFile(program, []byte("#!/bin/sh\necho \"$@\"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("EXEC_TEST_REMOVED", "x")
+	for _, removed := range []bool{false, true} {
+		cmd := exec.Command(program, "ran")
+		if removed {
+			for _, kv := range os.Environ() {
+				if !strings.HasPrefix(kv, "EXEC_TEST_REMOVED=") {
+					cmd.Env = append(cmd.Env, kv)
+				}
+			}
+		}
+		line := cmd.ShellString()
+		output, err := stdexec.Command("sh", "-c", line).Output()
+		if err != nil {
+			t.Fatalf("Failed to run %q: %v", line, err)
+		}
+		if string(output) != "ran\n" {
+			t.Errorf("Expected %q from %q, got %q", "ran\n", line, string(output))
+		}
+	}
+}
+
+func TestShellStringUsesPath(t *testing.T) {
+	cmd := exec.Command("echo", "hello")
+	cmd.Path = "/bin/true"
+	if line := cmd.ShellString(); line != "/bin/true hello" {
+		t.Errorf("Expected %q, got %q", "/bin/true hello", line)
+	}
+}
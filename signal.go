@@ -0,0 +1,41 @@
+package exec
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// NotifyAndForward forwards the given signals, when received by this process, to every running command created by
+// this package, until ctx is done.
+//
+// Signals are delivered to each command's whole process group, as a shell would deliver them to a foreground job.
+// While forwarding is active the signals no longer have their default effect on this process, so a CLI should handle
+// its own shutdown, typically by waiting for its children to exit. If no signals are given, SIGINT and SIGTERM are
+// forwarded.
+func NotifyAndForward(ctx context.Context, signals ...os.Signal) {
+	if len(signals) == 0 {
+		signals = []os.Signal{syscall.SIGINT, syscall.SIGTERM}
+	}
+	ch := make(chan os.Signal, 8)
+	signal.Notify(ch, signals...)
+	go func() {
+		defer signal.Stop(ch)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case sig := <-ch:
+				sysSig, ok := sig.(syscall.Signal)
+				if !ok {
+					continue
+				}
+				for _, target := range running() {
+					markSignalled(target.cmd)
+					_ = signalGroup(target.pid, sysSig)
+				}
+			}
+		}
+	}()
+}
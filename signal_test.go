@@ -0,0 +1,46 @@
+//go:build (linux || darwin) && (amd64 || arm64)
+
+package exec_test
+
+import (
+	"context"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/exec"
+)
+
+func TestNotifyAndForward(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	exec.NotifyAndForward(ctx, syscall.SIGUSR1)
+
+	cmd := exec.Command("sh", "-c", `trap "echo got-usr1; exit 0" USR1; while :; do sleep 0.05; done`)
+	var output strings.Builder
+	cmd.Stdout = &output
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Failed to start: %v", err)
+	}
+	// Give the shell time to install its trap.
+	time.Sleep(200 * time.Millisecond)
+
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGUSR1); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	go func() {
+		_ = cmd.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		_ = cmd.Process.Kill()
+		t.Fatal("Signal was not forwarded to the child")
+	}
+	if !strings.Contains(output.String(), "got-usr1") {
+		t.Errorf("Expected child to handle SIGUSR1, got output %q", output.String())
+	}
+}
@@ -0,0 +1,24 @@
+//go:build (linux || darwin) && (amd64 || arm64)
+
+package exec
+
+import (
+	"io/fs"
+)
+
+// EmbeddedSize returns the total size in bytes of the compressed intermediaries embedded in the binary.
+func EmbeddedSize() int64 {
+	var total int64
+	_ = fs.WalkDir(binaries, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		total += info.Size()
+		return nil
+	})
+	return total
+}
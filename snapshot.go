@@ -0,0 +1,28 @@
+package exec
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Snapshot captures the current working directory and environment into cmd, and returns it.
+//
+// os/exec resolves an unset Dir and Env when the command is started, so a concurrent os.Chdir or os.Setenv in another
+// goroutine can change what is executed. Calling Snapshot immediately after creating a command fixes both at that
+// point. A relative Dir is made absolute, and an existing Env is left unchanged.
+func Snapshot(cmd *Cmd) (*Cmd, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case cmd.Dir == "":
+		cmd.Dir = wd
+	case !filepath.IsAbs(cmd.Dir):
+		cmd.Dir = filepath.Join(wd, cmd.Dir)
+	}
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+	return cmd, nil
+}
@@ -0,0 +1,33 @@
+//go:build (linux || darwin) && (amd64 || arm64)
+
+package exec_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/alecthomas/exec"
+)
+
+func TestSnapshot(t *testing.T) {
+	before := t.TempDir()
+	t.Chdir(before)
+	t.Setenv("EXEC_TEST_VAR", "before")
+
+	cmd, err := exec.Snapshot(exec.Command("sh", "-c", `echo "$PWD $EXEC_TEST_VAR"`))
+	if err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+
+	t.Chdir(t.TempDir())
+	os.Setenv("EXEC_TEST_VAR", "after") //nolint
+
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("Command failed: %v", err)
+	}
+	expected := before + " before\n"
+	if string(output) != expected {
+		t.Errorf("Expected %q, got %q", expected, string(output))
+	}
+}
@@ -0,0 +1,82 @@
+package exec
+
+import (
+	"context"
+	"os"
+	"time"
+)
+
+// AuditIDEnv is the environment variable used to pass Policy.AuditID to children.
+const AuditIDEnv = "EXEC_AUDIT_ID"
+
+// Spawner creates commands.
+//
+// Services that run commands on behalf of requests should create commands through a Spawner carried in the request
+// context (see WithSpawner and SpawnerFromContext), so that request-scoped policy is applied consistently.
+type Spawner interface {
+	CommandContext(ctx context.Context, name string, arg ...string) *Cmd
+}
+
+// DefaultSpawner creates commands with CommandContext and no additional policy.
+var DefaultSpawner Spawner = defaultSpawner{}
+
+type defaultSpawner struct{}
+
+func (defaultSpawner) CommandContext(ctx context.Context, name string, arg ...string) *Cmd {
+	return CommandContext(ctx, name, arg...)
+}
+
+// Policy is applied to every command created by a Spawner returned from NewSpawner.
+//
+// Running commands as a different user is deliberately not supported: the intermediary must be able to signal its
+// parent to detect its death, which an unprivileged process cannot do across users.
+type Policy struct {
+	// Timeout bounds the lifetime of each command, in addition to any context deadline.
+	Timeout time.Duration
+	// Dir is the working directory for commands that do not set their own.
+	Dir string
+	// Env is appended to the inherited environment.
+	Env []string
+	// AuditID is passed to children in the EXEC_AUDIT_ID environment variable.
+	AuditID string
+}
+
+// NewSpawner returns a Spawner that applies policy to every command it creates.
+func NewSpawner(policy Policy) Spawner {
+	return policySpawner{policy}
+}
+
+type policySpawner struct{ policy Policy }
+
+func (p policySpawner) CommandContext(ctx context.Context, name string, arg ...string) *Cmd {
+	cancel := context.CancelFunc(func() {})
+	if p.policy.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, p.policy.Timeout)
+	}
+	cmd := CommandContext(ctx, name, arg...)
+	// Release the timer as soon as the command exits, rather than when the timeout expires.
+	cmd.cleanups = append(cmd.cleanups, cancel)
+	cmd.Dir = p.policy.Dir
+	if len(p.policy.Env) > 0 || p.policy.AuditID != "" {
+		cmd.Env = append(os.Environ(), p.policy.Env...)
+		if p.policy.AuditID != "" {
+			cmd.Env = append(cmd.Env, AuditIDEnv+"="+p.policy.AuditID)
+		}
+	}
+	return cmd
+}
+
+type spawnerKey struct{}
+
+// WithSpawner returns a context carrying spawner.
+func WithSpawner(ctx context.Context, spawner Spawner) context.Context {
+	return context.WithValue(ctx, spawnerKey{}, spawner)
+}
+
+// SpawnerFromContext returns the Spawner carried by ctx, or DefaultSpawner.
+func SpawnerFromContext(ctx context.Context) Spawner {
+	if spawner, ok := ctx.Value(spawnerKey{}).(Spawner); ok {
+		return spawner
+	}
+	return DefaultSpawner
+}
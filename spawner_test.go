@@ -0,0 +1,43 @@
+//go:build (linux || darwin) && (amd64 || arm64)
+
+package exec_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/exec"
+)
+
+func TestSpawnerFromContext(t *testing.T) {
+	if exec.SpawnerFromContext(context.Background()) != exec.DefaultSpawner {
+		t.Error("Expected DefaultSpawner when none is set")
+	}
+
+	dir := t.TempDir()
+	spawner := exec.NewSpawner(exec.Policy{Dir: dir, Env: []string{"EXEC_TEST_VAR=policy"}, AuditID: "req-123"})
+	ctx := exec.WithSpawner(context.Background(), spawner)
+
+	cmd := exec.SpawnerFromContext(ctx).CommandContext(ctx, "sh", "-c", `echo "$PWD $EXEC_TEST_VAR $EXEC_AUDIT_ID"`)
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("Command failed: %v", err)
+	}
+	expected := dir + " policy req-123\n"
+	if string(output) != expected {
+		t.Errorf("Expected %q, got %q", expected, string(output))
+	}
+}
+
+func TestSpawnerTimeout(t *testing.T) {
+	spawner := exec.NewSpawner(exec.Policy{Timeout: 100 * time.Millisecond})
+	start := time.Now()
+	err := spawner.CommandContext(context.Background(), "sleep", "5").Run()
+	if err == nil {
+		t.Fatal("Expected command to be killed by the policy timeout")
+	}
+	if duration := time.Since(start); duration > 2*time.Second {
+		t.Errorf("Command took too long to time out: %v", duration)
+	}
+}
@@ -0,0 +1,58 @@
+//go:build (linux || darwin) && (amd64 || arm64)
+
+package exec
+
+import (
+	"expvar"
+)
+
+// Stats are package-level statistics, for debugging services that spawn many commands.
+type Stats struct {
+	// Extracted is true once the intermediary has been successfully extracted.
+	Extracted bool `json:"extracted"`
+	// IntermediaryPath is the path the intermediary was extracted to.
+	IntermediaryPath string `json:"intermediaryPath,omitempty"`
+	// ExtractError is the error from extracting the intermediary, if any.
+	ExtractError string `json:"extractError,omitempty"`
+	// Mechanism used by the intermediary to detect parent death.
+	Mechanism Mechanism `json:"mechanism"`
+	// Created is the total number of commands created.
+	Created uint64 `json:"created"`
+	// Running is the number of started commands that have not yet been waited for.
+	Running int `json:"running"`
+	// Failures counts failures by category: "extract" for failed attempts to extract the intermediary, "start" for
+	// commands that failed to start, and "watchdog" for intermediaries reported by CheckWatchdog as having died.
+	Failures map[string]uint64 `json:"failures"`
+}
+
+// GetStats returns current package statistics.
+func GetStats() Stats {
+	stats := Stats{
+		Mechanism: Intermediary().Mechanism,
+		Created:   commandsCreated.Load(),
+		Running:   len(running()),
+		Failures: map[string]uint64{
+			"extract":  extractFailures.Load(),
+			"start":    startFailures.Load(),
+			"watchdog": watchdogFailures.Load(),
+		},
+	}
+	extractMu.Lock()
+	defer extractMu.Unlock()
+	if extractDone {
+		if extractErr != nil {
+			stats.ExtractError = extractErr.Error()
+		} else {
+			stats.Extracted = true
+			stats.IntermediaryPath = extractedPath
+		}
+	}
+	return stats
+}
+
+// PublishStats publishes GetStats() as an expvar with the given name, eg. for /debug/vars.
+//
+// Like expvar.Publish, it panics if name is already registered.
+func PublishStats(name string) {
+	expvar.Publish(name, expvar.Func(func() any { return GetStats() }))
+}
@@ -0,0 +1,52 @@
+//go:build (linux || darwin) && (amd64 || arm64)
+
+package exec_test
+
+import (
+	"encoding/json"
+	"expvar"
+	"testing"
+
+	"github.com/alecthomas/exec"
+)
+
+func TestGetStats(t *testing.T) {
+	before := exec.GetStats()
+	cmd := exec.Command("sleep", "10")
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Failed to start: %v", err)
+	}
+	defer func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	}()
+
+	stats := exec.GetStats()
+	if !stats.Extracted || stats.IntermediaryPath == "" {
+		t.Errorf("Expected intermediary to be extracted, got %+v", stats)
+	}
+	if stats.Created != before.Created+1 {
+		t.Errorf("Expected %d commands created, got %d", before.Created+1, stats.Created)
+	}
+	if stats.Running < 1 {
+		t.Errorf("Expected at least one running command, got %d", stats.Running)
+	}
+
+	failing := exec.Command("true")
+	failing.Dir = "/does/not/exist"
+	if err := failing.Start(); err == nil {
+		t.Fatal("Expected start to fail")
+	}
+	if failures := exec.GetStats().Failures["start"]; failures != before.Failures["start"]+1 {
+		t.Errorf("Expected %d start failures, got %d", before.Failures["start"]+1, failures)
+	}
+
+	exec.PublishStats("exec-test")
+	var published exec.Stats
+	if err := json.Unmarshal([]byte(expvar.Get("exec-test").String()), &published); err != nil {
+		t.Fatalf("Failed to decode published stats: %v", err)
+	}
+	if published.Mechanism != exec.MechanismPoll {
+		t.Errorf("Expected published mechanism %q, got %q", exec.MechanismPoll, published.Mechanism)
+	}
+}
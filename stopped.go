@@ -0,0 +1,124 @@
+//go:build (linux || darwin) && (amd64 || arm64)
+
+package exec
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// stoppedScript reports the shell's pid on the given descriptor, stops, and once continued execs the command in its
+// place, keeping the pid.
+const stoppedScript = `echo $$ >&%d; exec %d>&-; kill -STOP $$; exec "$@"`
+
+// stopTimeout bounds how long StartStopped waits for the command to stop itself.
+const stopTimeout = 5 * time.Second
+
+// StartStopped starts the command stopped before it begins executing, so that a debugger or tracer such as dlv, gdb or
+// strace can attach to it, and returns the pid of the command once it has stopped. Call Continue to let it run.
+//
+// The intermediary is running and supervising the command by the time it stops. The command is started by a shell
+// that stops itself, then execs the command in its place once continued, so a debugger attached while it is stopped
+// sees that exec, eg. with gdb's "catch exec". This rewrites Path and Args, and uses one additional file descriptor.
+func StartStopped(cmd *Cmd) (pid int, err error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close() //nolint
+	fd := 3 + len(cmd.ExtraFiles)
+	args := []string{"/bin/sh", "-c", fmt.Sprintf(stoppedScript, fd, fd), "sh", cmd.Path}
+	if len(cmd.Args) > 1 {
+		args = append(args, cmd.Args[1:]...)
+	}
+	cmd.Path = "/bin/sh"
+	cmd.Args = args
+	cmd.ExtraFiles = append(cmd.ExtraFiles, w)
+	err = cmd.Start()
+	cmd.ExtraFiles = cmd.ExtraFiles[:len(cmd.ExtraFiles)-1]
+	_ = w.Close()
+	if err != nil {
+		return 0, err
+	}
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil {
+		return 0, fmt.Errorf("command did not report its pid: %w", err)
+	}
+	pid, err = strconv.Atoi(strings.TrimSpace(line))
+	if err != nil {
+		return 0, err
+	}
+	// The pid is reported before the shell stops itself, and a SIGCONT sent in between would be lost.
+	deadline := time.Now().Add(stopTimeout)
+	for {
+		stopped, err := processStopped(pid)
+		switch {
+		case err != nil:
+			return 0, fmt.Errorf("command %d did not stop: %w", pid, err)
+		case stopped:
+			return pid, nil
+		case time.Now().After(deadline):
+			return 0, fmt.Errorf("command %d did not stop within %s", pid, stopTimeout)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// StartRequiringWatchdog starts the command, but only lets it begin executing once both of the intermediary's
+// watchdogs are confirmed to be supervising it, for commands with side effects that must not outlive this process. If
+// they cannot be confirmed, the command is killed before it runs and an error wrapping ErrWatchdogNotArmed is
+// returned. Otherwise call Wait as usual.
+//
+// It is built on StartStopped, so the same rewriting of Path and Args applies. A command run directly by
+// StrategyPdeathsig has no watchdog to confirm, as the kernel sets its parent-death signal before it starts.
+func StartRequiringWatchdog(cmd *Cmd) error {
+	pid, err := StartStopped(cmd)
+	if err == nil && !cmd.direct {
+		if err = checkArmed(cmd.Process.Pid, pid); err != nil {
+			err = fmt.Errorf("%w: %w", ErrWatchdogNotArmed, err)
+		}
+	}
+	if err != nil {
+		if cmd.Process != nil {
+			markSignalled(cmd)
+			_ = signalGroup(cmd.Process.Pid, syscall.SIGKILL)
+			_ = cmd.Wait()
+		}
+		return err
+	}
+	return Continue(cmd)
+}
+
+// checkArmed checks that the command with the given pid is supervised by the intermediary leading the process group
+// pgid: its parent must be the inner watchdog, whose parent must in turn be the intermediary.
+func checkArmed(pgid, pid int) error {
+	parents, err := groupParents(pgid)
+	if err != nil {
+		return err
+	}
+	if _, ok := parents[pgid]; !ok {
+		return fmt.Errorf("intermediary %d is not running", pgid)
+	}
+	watchdog, ok := parents[pid]
+	if !ok {
+		return fmt.Errorf("command %d is not in the intermediary's process group", pid)
+	}
+	if parents[watchdog] != pgid {
+		return fmt.Errorf("parent %d of command %d is not supervised by intermediary %d", watchdog, pid, pgid)
+	}
+	return nil
+}
+
+// Continue resumes a command started with StartStopped.
+func Continue(cmd *Cmd) error {
+	if cmd.Process == nil {
+		return errors.New("exec: not started")
+	}
+	return signalGroup(cmd.Process.Pid, syscall.SIGCONT)
+}
@@ -0,0 +1,25 @@
+//go:build amd64 || arm64
+
+package exec
+
+import (
+	"bytes"
+	"errors"
+	"os/exec"
+	"strconv"
+)
+
+// processStopped reports whether pid is stopped by a signal, or an error if it has exited.
+//
+// macOS has no /proc, so this asks ps.
+func processStopped(pid int) (bool, error) {
+	out, err := exec.Command("/bin/ps", "-o", "stat=", "-p", strconv.Itoa(pid)).Output()
+	if err != nil {
+		return false, err
+	}
+	state := bytes.TrimSpace(out)
+	if len(state) == 0 || state[0] == 'Z' {
+		return false, errors.New("process exited")
+	}
+	return state[0] == 'T', nil
+}
@@ -0,0 +1,24 @@
+//go:build amd64 || arm64
+
+package exec
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strconv"
+)
+
+// processStopped reports whether pid is stopped by a signal, or an error if it has exited.
+func processStopped(pid int) (bool, error) {
+	stat, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return false, err
+	}
+	// The state follows the parenthesised command name, which may itself contain parentheses.
+	fields := bytes.Fields(stat[bytes.LastIndexByte(stat, ')')+1:])
+	if len(fields) == 0 || fields[0][0] == 'Z' {
+		return false, errors.New("process exited")
+	}
+	return fields[0][0] == 'T', nil
+}
@@ -0,0 +1,93 @@
+//go:build (linux || darwin) && (amd64 || arm64)
+
+package exec_test
+
+import (
+	"bytes"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/exec"
+)
+
+func TestStartStoppedContinueImmediately(t *testing.T) {
+	for range 50 {
+		cmd := exec.Command("true")
+		if _, err := exec.StartStopped(cmd); err != nil {
+			t.Fatal(err)
+		}
+		if err := exec.Continue(cmd); err != nil {
+			t.Fatal(err)
+		}
+		waitWithin(t, cmd, 2*time.Second)
+	}
+}
+
+// waitWithin waits for cmd, failing the test and killing cmd if it does not exit within d.
+func waitWithin(t *testing.T, cmd *exec.Cmd, d time.Duration) {
+	t.Helper()
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(d):
+		_ = syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		<-done
+		t.Fatalf("Command did not exit within %s", d)
+	}
+}
+
+func TestStartStopped(t *testing.T) {
+	cmd := exec.Command("sh", "-c", "echo $$")
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	pid, err := exec.StartStopped(cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The command must already be stopped, and must not run until continued.
+	if status, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat"); err == nil && !bytes.Contains(status, []byte(") T ")) {
+		t.Errorf("Expected %d to be stopped, got %s", pid, status)
+	}
+	if err := exec.Continue(cmd); err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(stdout.String()); got != strconv.Itoa(pid) {
+		t.Errorf("Expected the command to run as pid %d, got %q", pid, got)
+	}
+}
+
+func TestStartRequiringWatchdog(t *testing.T) {
+	cmd := exec.Command("echo", "armed")
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	if err := exec.StartRequiringWatchdog(cmd); err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if stdout.String() != "armed\n" {
+		t.Errorf("Expected %q, got %q", "armed\n", stdout.String())
+	}
+}
+
+func TestStartRequiringWatchdogContinues(t *testing.T) {
+	for range 100 {
+		cmd := exec.Command("true")
+		if err := exec.StartRequiringWatchdog(cmd); err != nil {
+			t.Fatal(err)
+		}
+		waitWithin(t, cmd, 2*time.Second)
+	}
+}
@@ -0,0 +1,53 @@
+//go:build (linux || darwin) && (amd64 || arm64)
+
+package exec
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// Strategy is the method used to guarantee that commands terminate when this process dies.
+type Strategy int
+
+const (
+	// StrategyIntermediary runs commands via the embedded intermediary, which terminates the command's whole process
+	// group when this process dies. This is the default.
+	StrategyIntermediary Strategy = iota
+	// StrategyPdeathsig runs commands directly, with the kernel sending the command SIGKILL when this process dies.
+	//
+	// No intermediary is extracted or run, so starting a command is faster and leaves nothing on disk, and the
+	// command is this process's direct child. However only the command itself is killed, not any processes it has
+	// started, and the exit status of a command killed by a signal is reported as such rather than as 128+signal.
+	// The signal is sent when the OS thread that started the command exits, so commands must not be started from a
+	// goroutine that calls runtime.LockOSThread without unlocking it. It is only available on Linux.
+	StrategyPdeathsig
+)
+
+func (s Strategy) String() string {
+	switch s {
+	case StrategyIntermediary:
+		return "intermediary"
+	case StrategyPdeathsig:
+		return "pdeathsig"
+	default:
+		return fmt.Sprintf("Strategy(%d)", int(s))
+	}
+}
+
+var strategy = StrategyIntermediary
+
+// SetStrategy sets the method used to guarantee that commands terminate when this process dies.
+//
+// It returns an error if the strategy is not available on the current platform. It must be called before the first
+// command is created.
+func SetStrategy(s Strategy) error {
+	switch {
+	case s == StrategyPdeathsig && !pdeathsigSupported:
+		return fmt.Errorf("exec: strategy %s is not supported on %s", s, runtime.GOOS)
+	case s != StrategyIntermediary && s != StrategyPdeathsig:
+		return fmt.Errorf("exec: unknown strategy %s", s)
+	}
+	strategy = s
+	return nil
+}
@@ -0,0 +1,10 @@
+//go:build amd64 || arm64
+
+package exec
+
+import "syscall"
+
+// macOS has no equivalent of PR_SET_PDEATHSIG.
+const pdeathsigSupported = false
+
+func setPdeathsig(attr *syscall.SysProcAttr) {}
@@ -0,0 +1,12 @@
+//go:build amd64 || arm64
+
+package exec
+
+import "syscall"
+
+const pdeathsigSupported = true
+
+// setPdeathsig arranges for the kernel to send SIGKILL to the command when the thread that starts it exits.
+func setPdeathsig(attr *syscall.SysProcAttr) {
+	attr.Pdeathsig = syscall.SIGKILL
+}
@@ -0,0 +1,47 @@
+//go:build amd64 || arm64
+
+package exec_test
+
+import (
+	"testing"
+
+	stdexec "os/exec"
+
+	"github.com/alecthomas/exec"
+)
+
+func TestStrategyPdeathsig(t *testing.T) {
+	if err := exec.SetStrategy(exec.StrategyPdeathsig); err != nil {
+		t.Fatal(err)
+	}
+	defer exec.SetStrategy(exec.StrategyIntermediary) //nolint
+
+	if info := exec.Intermediary(); info.Mechanism != exec.MechanismPdeathsig {
+		t.Errorf("Expected mechanism %q, got %q", exec.MechanismPdeathsig, info.Mechanism)
+	}
+
+	cmd := exec.Command("echo", "direct")
+	if std := cmd.Unwrap(); std.Args[0] != "echo" || std.SysProcAttr.Pdeathsig == 0 {
+		t.Errorf("Expected command to run directly with a parent-death signal, got args %v", std.Args)
+	}
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("Command failed: %v", err)
+	}
+	if string(output) != "direct\n" {
+		t.Errorf("Expected %q, got %q", "direct\n", string(output))
+	}
+
+	std := stdexec.Command("echo", "from", "std")
+	output, err = exec.NewFromStd(std).Output()
+	if err != nil {
+		t.Fatalf("Command failed: %v", err)
+	}
+	if string(output) != "from std\n" {
+		t.Errorf("Expected %q, got %q", "from std\n", string(output))
+	}
+
+	if err := exec.SetStrategy(exec.Strategy(42)); err == nil {
+		t.Error("Expected an unknown strategy to be rejected")
+	}
+}
@@ -0,0 +1,96 @@
+package exec
+
+import (
+	"bufio"
+	"io"
+)
+
+// streamChunkSize is the maximum size of each chunk sent by StdoutToChannel.
+const streamChunkSize = 32 * 1024
+
+// StdinFromChannel feeds the command's stdin from in. Stdin is closed once in is closed.
+//
+// Each chunk is written to the child before the next is received, so a child that reads slowly applies backpressure
+// to the sender. Wait does not return until in is closed, unless WaitDelay is set.
+func StdinFromChannel(cmd *Cmd, in <-chan []byte) {
+	cmd.Stdin = &channelReader{ch: in}
+}
+
+type channelReader struct {
+	ch      <-chan []byte
+	pending []byte
+}
+
+func (c *channelReader) Read(p []byte) (int, error) {
+	for len(c.pending) == 0 {
+		chunk, ok := <-c.ch
+		if !ok {
+			return 0, io.EOF
+		}
+		c.pending = chunk
+	}
+	n := copy(p, c.pending)
+	c.pending = c.pending[n:]
+	return n, nil
+}
+
+// StdoutToChannel sends the command's stdout to out in chunks, closing out at EOF. It must be called before Start.
+//
+// Chunks are not sent until out has capacity, so the capacity of out bounds buffering, and a slow receiver
+// applies backpressure to the child. All output must be received from out before calling Wait.
+func StdoutToChannel(cmd *Cmd, out chan<- []byte) error {
+	r, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	go func() {
+		defer close(out)
+		buf := make([]byte, streamChunkSize)
+		for {
+			n, err := r.Read(buf)
+			if n > 0 {
+				out <- append([]byte(nil), buf[:n]...)
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// StreamRecords sends the command's stdout to out as records terminated by delim, closing out at EOF. It must be
+// called before Start.
+//
+// Records are sent without the delimiter, and a final record without a trailing delimiter is also sent. Records are
+// not otherwise interpreted, so arbitrary binary output is passed through intact. As with StdoutToChannel, all
+// records must be received from out before calling Wait.
+func StreamRecords(cmd *Cmd, delim byte, out chan<- []byte) error {
+	r, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	go func() {
+		defer close(out)
+		br := bufio.NewReaderSize(r, streamChunkSize)
+		for {
+			record, err := br.ReadBytes(delim)
+			if n := len(record); n > 0 {
+				if record[n-1] == delim {
+					record = record[:n-1]
+				}
+				out <- record
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// StreamNull sends the command's stdout to out as NUL-terminated records, as written by "find -print0" and
+// "xargs -0". See StreamRecords.
+func StreamNull(cmd *Cmd, out chan<- []byte) error {
+	return StreamRecords(cmd, 0, out)
+}
@@ -0,0 +1,69 @@
+//go:build (linux || darwin) && (amd64 || arm64)
+
+package exec_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/alecthomas/exec"
+)
+
+func TestChannelStreaming(t *testing.T) {
+	cmd := exec.Command("tr", "a-z", "A-Z")
+	in := make(chan []byte)
+	out := make(chan []byte, 1)
+	exec.StdinFromChannel(cmd, in)
+	if err := exec.StdoutToChannel(cmd, out); err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Failed to start: %v", err)
+	}
+
+	go func() {
+		for _, chunk := range []string{"hello ", "streaming ", "world"} {
+			in <- []byte(chunk)
+		}
+		close(in)
+	}()
+
+	var output bytes.Buffer
+	for chunk := range out {
+		output.Write(chunk)
+	}
+	if err := cmd.Wait(); err != nil {
+		t.Fatalf("Command failed: %v", err)
+	}
+	if output.String() != "HELLO STREAMING WORLD" {
+		t.Errorf("Expected %q, got %q", "HELLO STREAMING WORLD", output.String())
+	}
+}
+
+func TestStreamNull(t *testing.T) {
+	cmd := exec.Command("printf", `a b\000c\nd\000\000last`)
+	out := make(chan []byte)
+	if err := exec.StreamNull(cmd, out); err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Failed to start: %v", err)
+	}
+	var records []string
+	for record := range out {
+		records = append(records, string(record))
+	}
+	if err := cmd.Wait(); err != nil {
+		t.Fatalf("Command failed: %v", err)
+	}
+	expected := []string{"a b", "c\nd", "", "last"}
+	if len(records) != len(expected) {
+		t.Fatalf("Expected %q, got %q", expected, records)
+	}
+	for i := range expected {
+		if records[i] != expected[i] {
+			t.Errorf("Expected %q, got %q", expected, records)
+			break
+		}
+	}
+}
@@ -0,0 +1,328 @@
+package exec
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Preflight resolves each of names with LookPath concurrently, and returns a map from name to path.
+//
+// If any names cannot be resolved, the error reports every one of them, in the order given, and the map contains only
+// those that were resolved.
+func Preflight(names ...string) (map[string]string, error) {
+	paths := make([]string, len(names))
+	errs := make([]error, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Go(func() {
+			paths[i], errs[i] = LookPath(name)
+		})
+	}
+	wg.Wait()
+	resolved := make(map[string]string, len(names))
+	for i, name := range names {
+		if errs[i] == nil {
+			resolved[name] = paths[i]
+		}
+	}
+	return resolved, errors.Join(errs...)
+}
+
+// Tool resolution and version probing results are cached, so that programs that create many commands do not
+// repeatedly search $PATH or run "--version".
+var (
+	// alternatives caches the result of FirstAvailable by list of names.
+	alternatives = newToolCache[string]()
+	// variants caches the result of ToolVariant by path.
+	variants = newToolCache[Variant]()
+	// versions caches the result of Version by path.
+	versions     = newToolCache[string]()
+	toolCacheTTL atomic.Int64
+)
+
+// SetToolCacheTTL sets how long the results of FirstAvailable, ToolVariant and Version are cached. The default of
+// zero caches results until InvalidateToolCache is called.
+func SetToolCacheTTL(ttl time.Duration) {
+	toolCacheTTL.Store(int64(ttl))
+}
+
+// InvalidateToolCache discards the cached results of FirstAvailable, ToolVariant and Version, eg. after installing a
+// tool.
+func InvalidateToolCache() {
+	alternatives.clear()
+	variants.clear()
+	versions.clear()
+}
+
+type toolCacheEntry[V any] struct {
+	value  V
+	stored time.Time
+}
+
+type toolCache[V any] struct {
+	lock    sync.Mutex
+	entries map[string]toolCacheEntry[V]
+}
+
+func newToolCache[V any]() *toolCache[V] {
+	return &toolCache[V]{entries: map[string]toolCacheEntry[V]{}}
+}
+
+func (c *toolCache[V]) get(key string) (V, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	entry, ok := c.entries[key]
+	if ttl := time.Duration(toolCacheTTL.Load()); ok && ttl > 0 && time.Since(entry.stored) > ttl {
+		delete(c.entries, key)
+		ok = false
+	}
+	return entry.value, ok
+}
+
+func (c *toolCache[V]) set(key string, value V) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.entries[key] = toolCacheEntry[V]{value: value, stored: time.Now()}
+}
+
+func (c *toolCache[V]) clear() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	clear(c.entries)
+}
+
+// FirstAvailable returns the path of the first of names found by LookPath, eg. FirstAvailable("fd", "fdfind") to
+// handle a tool that Debian installs under a different name.
+//
+// The choice is cached, so later calls with the same names do not search $PATH again (see SetToolCacheTTL). If none
+// are found, the error wraps ErrNotFound.
+func FirstAvailable(names ...string) (string, error) {
+	key := strings.Join(names, "\x00")
+	if path, ok := alternatives.get(key); ok {
+		return path, nil
+	}
+	for _, name := range names {
+		if path, err := LookPath(name); err == nil {
+			alternatives.set(key, path)
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("none of %s: %w", strings.Join(names, ", "), ErrNotFound)
+}
+
+// Variant identifies which implementation of a standard Unix tool is installed.
+type Variant int
+
+const (
+	VariantUnknown Variant = iota
+	VariantGNU
+	VariantBSD
+	VariantBusyBox
+)
+
+func (v Variant) String() string {
+	switch v {
+	case VariantGNU:
+		return "GNU"
+	case VariantBSD:
+		return "BSD"
+	case VariantBusyBox:
+		return "BusyBox"
+	default:
+		return "unknown"
+	}
+}
+
+// ToolVariant reports whether the named tool, such as sed, date, stat or xargs, is the GNU, BSD or BusyBox
+// implementation, so that callers can pass the right flags. For example, "sed -i" edits in place with GNU and
+// BusyBox sed, but BSD sed requires an explicit, possibly empty, backup suffix argument.
+//
+// The variant is detected by running "name --version", which BSD tools reject, and is cached for each path.
+func ToolVariant(name string) (Variant, error) {
+	path, err := LookPath(name)
+	if err != nil {
+		return VariantUnknown, err
+	}
+	if variant, ok := variants.get(path); ok {
+		return variant, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), versionTimeout)
+	defer cancel()
+	output, err := CommandContext(ctx, path, "--version").CombinedOutput()
+	var variant Variant
+	switch {
+	// BusyBox sed claims to be "not GNU sed", so check for BusyBox first.
+	case bytes.Contains(output, []byte("BusyBox")):
+		variant = VariantBusyBox
+	case bytes.Contains(output, []byte("GNU")) || bytes.Contains(output, []byte("Free Software Foundation")):
+		variant = VariantGNU
+	case errors.As(err, new(*ExitError)):
+		variant = VariantBSD
+	case err != nil:
+		return VariantUnknown, err
+	}
+	variants.set(path, variant)
+	return variant, nil
+}
+
+// versionTimeout bounds how long Version waits for a tool to report its version.
+const versionTimeout = 5 * time.Second
+
+var (
+	versionPattern     = regexp.MustCompile(`\d+(?:\.\d+)+`)
+	requirementPattern = regexp.MustCompile(`^([^<>=!\s]+)\s*(?:(>=|<=|==|!=|>|<|=)\s*(\S+))?$`)
+)
+
+// Version runs "name --version" and returns the first dotted version number in its output, eg. "2.43.0".
+//
+// The version is cached for each path.
+func Version(name string) (string, error) {
+	path, err := LookPath(name)
+	if err != nil {
+		return "", err
+	}
+	if version, ok := versions.get(path); ok {
+		return version, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), versionTimeout)
+	defer cancel()
+	output, err := CommandContext(ctx, path, "--version").CombinedOutput()
+	if version := versionPattern.Find(output); version != nil {
+		versions.set(path, string(version))
+		return string(version), nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("%s --version: %w", name, err)
+	}
+	return "", fmt.Errorf("%s --version: no version number in output", name)
+}
+
+// RequireError is returned by Require when requirements are not met.
+type RequireError struct {
+	// Problems has an entry for each requirement that was not met, in the order given.
+	Problems []RequireProblem
+}
+
+// RequireProblem describes a single requirement that was not met.
+type RequireProblem struct {
+	// Requirement as passed to Require, eg. "git>=2.30".
+	Requirement string
+	// Path the tool was found at, or empty if it is missing.
+	Path string
+	// Version of the tool found, or empty if it is missing or its version could not be determined.
+	Version string
+	Err     error
+}
+
+func (e *RequireError) Error() string {
+	var b strings.Builder
+	b.WriteString("missing or outdated tools:")
+	for _, problem := range e.Problems {
+		fmt.Fprintf(&b, "\n  %s: %s", problem.Requirement, problem.Err)
+	}
+	return b.String()
+}
+
+// Require checks that each required tool is installed, returning a *RequireError describing every unmet requirement.
+//
+// Each requirement is a name, optionally followed by an operator (>=, >, <=, <, = or !=) and a dotted version, eg.
+// "git>=2.30". Versions are determined with Version, and compared numerically component by component.
+func Require(requirements ...string) error {
+	names := make([]string, len(requirements))
+	for i, requirement := range requirements {
+		if match := requirementPattern.FindStringSubmatch(requirement); match != nil {
+			names[i] = match[1]
+		}
+	}
+	paths, _ := Preflight(names...)
+	problems := make([]*RequireProblem, len(requirements))
+	var wg sync.WaitGroup
+	for i, requirement := range requirements {
+		wg.Go(func() {
+			problems[i] = checkRequirement(requirement, paths)
+		})
+	}
+	wg.Wait()
+	err := &RequireError{}
+	for _, problem := range problems {
+		if problem != nil {
+			err.Problems = append(err.Problems, *problem)
+		}
+	}
+	if len(err.Problems) == 0 {
+		return nil
+	}
+	return err
+}
+
+func checkRequirement(requirement string, paths map[string]string) *RequireProblem {
+	problem := &RequireProblem{Requirement: requirement}
+	match := requirementPattern.FindStringSubmatch(requirement)
+	if match == nil {
+		problem.Err = errors.New("invalid requirement")
+		return problem
+	}
+	name, op, want := match[1], match[2], match[3]
+	problem.Path = paths[name]
+	if problem.Path == "" {
+		problem.Err = fmt.Errorf("%s not found in $PATH", name)
+		return problem
+	}
+	if op == "" {
+		return nil
+	}
+	version, err := Version(problem.Path)
+	if err != nil {
+		problem.Err = err
+		return problem
+	}
+	problem.Version = version
+	order := compareVersions(version, want)
+	var ok bool
+	switch op {
+	case ">=":
+		ok = order >= 0
+	case ">":
+		ok = order > 0
+	case "<=":
+		ok = order <= 0
+	case "<":
+		ok = order < 0
+	case "=", "==":
+		ok = order == 0
+	case "!=":
+		ok = order != 0
+	}
+	if ok {
+		return nil
+	}
+	problem.Err = fmt.Errorf("found version %s at %s", version, problem.Path)
+	return problem
+}
+
+// compareVersions compares dotted versions numerically, treating missing components as zero.
+func compareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := range max(len(as), len(bs)) {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if x != y {
+			return cmp.Compare(x, y)
+		}
+	}
+	return 0
+}
@@ -0,0 +1,134 @@
+//go:build (linux || darwin) && (amd64 || arm64)
+
+package exec_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/exec"
+)
+
+func TestPreflight(t *testing.T) {
+	paths, err := exec.Preflight("sh", "missing-tool-one", "missing-tool-two")
+	if paths["sh"] == "" {
+		t.Errorf("Expected sh to be resolved, got %v", paths)
+	}
+	if len(paths) != 1 {
+		t.Errorf("Expected only resolved tools in result, got %v", paths)
+	}
+	var execErr *exec.Error
+	if !errors.As(err, &execErr) || execErr.Name != "missing-tool-one" {
+		t.Fatalf("Expected error for missing-tool-one, got %v", err)
+	}
+	if expected := "exec: \"missing-tool-one\": executable file not found in $PATH\nexec: \"missing-tool-two\": executable file not found in $PATH"; err.Error() != expected {
+		t.Errorf("Expected %q, got %q", expected, err.Error())
+	}
+}
+
+// fakeTool creates a shell script in a new directory on $PATH.
+func fakeTool(t *testing.T, name, script string) {
+	t.Helper()
+	dir := t.TempDir()
+	script = "#!/bin/sh\n" + script + "\n"
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(script), 0700); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestVersion(t *testing.T) {
+	fakeTool(t, "fake-tool", "echo fake-tool version 2.43.1 '(build 7)'")
+	version, err := exec.Version("fake-tool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != "2.43.1" {
+		t.Errorf("Expected %q, got %q", "2.43.1", version)
+	}
+}
+
+func TestRequire(t *testing.T) {
+	fakeTool(t, "fake-tool", "echo fake-tool 2.9.0")
+	if err := exec.Require("sh", "fake-tool>=2.9", "fake-tool<2.10", "fake-tool!=1"); err != nil {
+		t.Errorf("Expected requirements to be met, got %v", err)
+	}
+	err := exec.Require("fake-tool>=2.30", "missing-tool", "fake-tool")
+	var requireErr *exec.RequireError
+	if !errors.As(err, &requireErr) {
+		t.Fatalf("Expected *RequireError, got %v", err)
+	}
+	if len(requireErr.Problems) != 2 {
+		t.Fatalf("Expected 2 problems, got %v", err)
+	}
+	if problem := requireErr.Problems[0]; problem.Version != "2.9.0" || problem.Path == "" {
+		t.Errorf("Expected outdated fake-tool 2.9.0, got %+v", problem)
+	}
+	if problem := requireErr.Problems[1]; problem.Requirement != "missing-tool" || problem.Path != "" {
+		t.Errorf("Expected missing-tool to be missing, got %+v", problem)
+	}
+	expected := "missing or outdated tools:\n  fake-tool>=2.30: found version 2.9.0 at "
+	if !strings.HasPrefix(err.Error(), expected) || !strings.HasSuffix(err.Error(), "\n  missing-tool: missing-tool not found in $PATH") {
+		t.Errorf("Unexpected report:\n%s", err)
+	}
+}
+
+func TestFirstAvailable(t *testing.T) {
+	fakeTool(t, "fake-fdfind", "")
+	path, err := exec.FirstAvailable("fake-fd", "fake-fdfind", "sh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(path) != "fake-fdfind" {
+		t.Errorf("Expected fake-fdfind, got %q", path)
+	}
+	_, err = exec.FirstAvailable("missing-tool-one", "missing-tool-two")
+	if !errors.Is(err, exec.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestToolVariant(t *testing.T) {
+	fakeTool(t, "fake-gnu-sed", "echo 'sed (GNU sed) 4.9'")
+	fakeTool(t, "fake-bsd-sed", "echo 'sed: illegal option -- -' >&2; exit 1")
+	fakeTool(t, "fake-busybox-sed", "echo 'This is not GNU sed version 4.0'; echo 'BusyBox v1.36.1'")
+	for name, expected := range map[string]exec.Variant{
+		"fake-gnu-sed":     exec.VariantGNU,
+		"fake-bsd-sed":     exec.VariantBSD,
+		"fake-busybox-sed": exec.VariantBusyBox,
+	} {
+		variant, err := exec.ToolVariant(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if variant != expected {
+			t.Errorf("%s: expected %s, got %s", name, expected, variant)
+		}
+	}
+}
+
+func TestInvalidateToolCache(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	tool := filepath.Join(dir, "fake-cached-tool")
+	write := func(version string) {
+		if err := os.WriteFile(tool, []byte("#!/bin/sh\necho "+version+"\n"), 0700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("1.0")
+	if version, err := exec.Version("fake-cached-tool"); err != nil || version != "1.0" {
+		t.Fatalf("Expected 1.0, got %q (%v)", version, err)
+	}
+	write("2.0")
+	if version, _ := exec.Version("fake-cached-tool"); version != "1.0" {
+		t.Errorf("Expected cached version 1.0, got %q", version)
+	}
+	exec.InvalidateToolCache()
+	if version, _ := exec.Version("fake-cached-tool"); version != "2.0" {
+		t.Errorf("Expected 2.0 after invalidation, got %q", version)
+	}
+}
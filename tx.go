@@ -0,0 +1,87 @@
+package exec
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// Tx runs a sequence of commands, each with an optional compensating command that undoes it, so that a failed
+// sequence can be rolled back. The zero value is ready to use.
+type Tx struct {
+	// RollbackTimeout bounds the run time of each rollback command, after which its process group is killed. Zero
+	// means no limit.
+	RollbackTimeout time.Duration
+
+	lock      sync.Mutex
+	rollbacks []*Cmd
+}
+
+// Run cmd as the next step of the transaction, registering rollback, which may be nil, to undo it.
+//
+// If cmd fails, the rollbacks of all previous steps are run in reverse order, and the returned error includes any
+// errors from them. The rollback for a failed step is not run.
+func (tx *Tx) Run(cmd *Cmd, rollback *Cmd) error {
+	if err := cmd.Run(); err != nil {
+		err = fmt.Errorf("%s: %w", cmd.ShellString(), err)
+		if rerr := tx.Rollback(); rerr != nil {
+			return errors.Join(err, fmt.Errorf("rollback: %w", rerr))
+		}
+		return err
+	}
+	tx.lock.Lock()
+	defer tx.lock.Unlock()
+	tx.rollbacks = append(tx.rollbacks, rollback)
+	return nil
+}
+
+// Rollback runs the rollbacks of all completed steps in reverse order.
+//
+// Every rollback is attempted even if earlier ones fail, and their errors are returned together. The transaction is
+// empty afterwards.
+func (tx *Tx) Rollback() error {
+	tx.lock.Lock()
+	rollbacks := tx.rollbacks
+	tx.rollbacks = nil
+	tx.lock.Unlock()
+	var errs []error
+	for i := len(rollbacks) - 1; i >= 0; i-- {
+		if rollbacks[i] == nil {
+			continue
+		}
+		if err := tx.runRollback(rollbacks[i]); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", rollbacks[i].ShellString(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Commit discards the rollbacks of all completed steps, so that they can no longer be rolled back.
+func (tx *Tx) Commit() {
+	tx.lock.Lock()
+	defer tx.lock.Unlock()
+	tx.rollbacks = nil
+}
+
+func (tx *Tx) runRollback(cmd *Cmd) error {
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	var timedOut atomic.Bool
+	if tx.RollbackTimeout > 0 {
+		timer := time.AfterFunc(tx.RollbackTimeout, func() {
+			timedOut.Store(true)
+			markSignalled(cmd)
+			_ = signalGroup(cmd.Process.Pid, syscall.SIGKILL)
+		})
+		defer timer.Stop()
+	}
+	err := cmd.Wait()
+	if timedOut.Load() {
+		return fmt.Errorf("timed out after %s: %w", tx.RollbackTimeout, err)
+	}
+	return err
+}
@@ -0,0 +1,59 @@
+//go:build (linux || darwin) && (amd64 || arm64)
+
+package exec_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/exec"
+)
+
+func TestTxRollback(t *testing.T) {
+	log := filepath.Join(t.TempDir(), "log")
+	step := func(action string) *exec.Cmd {
+		return exec.Command("sh", "-c", `echo "$0" >> "$1"`, action, log)
+	}
+	tx := exec.Tx{RollbackTimeout: 200 * time.Millisecond}
+	if err := tx.Run(step("create a"), step("delete a")); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Run(step("create b"), exec.Command("sleep", "10")); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Run(step("create c"), step("delete c")); err != nil {
+		t.Fatal(err)
+	}
+	sleepPath, err := exec.LookPath("sleep")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = tx.Run(exec.Command("false"), step("never"))
+	if err == nil {
+		t.Fatal("Expected failure")
+	}
+	if !strings.Contains(err.Error(), "false: exit status 1") || !strings.Contains(err.Error(), "rollback: "+sleepPath+" 10: timed out after 200ms") {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	output, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "create a\ncreate b\ncreate c\ndelete c\ndelete a\n"; string(output) != expected {
+		t.Errorf("Expected %q, got %q", expected, output)
+	}
+}
+
+func TestTxCommit(t *testing.T) {
+	var tx exec.Tx
+	if err := tx.Run(exec.Command("true"), exec.Command("false")); err != nil {
+		t.Fatal(err)
+	}
+	tx.Commit()
+	if err := tx.Rollback(); err != nil {
+		t.Errorf("Expected nothing to roll back after Commit, got %v", err)
+	}
+}
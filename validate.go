@@ -0,0 +1,61 @@
+//go:build (linux || darwin) && (amd64 || arm64)
+
+package exec
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Validate checks cmd for common mistakes before it is started, and returns every problem found.
+//
+// In addition to the checks made by Prepare, CheckArgs and CheckArchitecture, it reports environment variables that are
+// malformed or set more than once, as *EnvError, and Stdin, Stdout or Stderr set to a typed nil such as a nil
+// *bytes.Buffer, which panics when the command runs.
+func Validate(cmd *Cmd) error {
+	var errs []error
+	if err := Prepare(cmd); err != nil {
+		errs = append(errs, err)
+	}
+	if err := CheckArgs(cmd); err != nil {
+		errs = append(errs, err)
+	}
+	if err := CheckArchitecture(cmd); err != nil {
+		errs = append(errs, err)
+	}
+	seen := map[string]int{}
+	for i, kv := range cmd.Env {
+		name, _, ok := strings.Cut(kv, "=")
+		if !ok {
+			errs = append(errs, &EnvError{Name: kv, Index: i, Reason: "has no '=': use NAME=value"})
+			continue
+		}
+		seen[name]++
+		if seen[name] == 2 {
+			errs = append(errs, &EnvError{Name: name, Index: i, Reason: "is set more than once: only the last value is used"})
+		}
+	}
+	for _, stream := range []struct {
+		name  string
+		value any
+	}{{"Stdin", cmd.Stdin}, {"Stdout", cmd.Stdout}, {"Stderr", cmd.Stderr}} {
+		if isTypedNil(stream.value) {
+			errs = append(errs, fmt.Errorf("%s is a nil %T: set it to nil to use the null device", stream.name, stream.value))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func isTypedNil(v any) bool {
+	if v == nil {
+		return false
+	}
+	switch value := reflect.ValueOf(v); value.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Chan, reflect.Func, reflect.Slice, reflect.Interface:
+		return value.IsNil()
+	default:
+		return false
+	}
+}
@@ -0,0 +1,45 @@
+//go:build (linux || darwin) && (amd64 || arm64)
+
+package exec_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/exec"
+)
+
+func TestValidate(t *testing.T) {
+	var buf bytes.Buffer
+	cmd := exec.Command("echo", "hello")
+	cmd.Stdout = &buf
+	if err := exec.Validate(cmd); err != nil {
+		t.Errorf("Expected valid command, got %v", err)
+	}
+
+	var nilBuf *bytes.Buffer
+	cmd = exec.Command("definitely-not-a-real-command", "a\x00b")
+	cmd.Env = []string{"A=1", "B", "A=2"}
+	cmd.Stdout = nilBuf
+	err := exec.Validate(cmd)
+	if err == nil {
+		t.Fatal("Expected validation errors")
+	}
+	for _, expected := range []string{
+		"executable file not found",
+		"argument 1 contains a NUL byte",
+		"environment variable B has no '='",
+		"environment variable A is set more than once",
+		"Stdout is a nil *bytes.Buffer",
+	} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected %q in errors, got:\n%v", expected, err)
+		}
+	}
+	var envErr *exec.EnvError
+	if !errors.As(err, &envErr) || envErr.Name != "B" || envErr.Index != 1 {
+		t.Errorf("Expected an *EnvError naming B, got %#v", envErr)
+	}
+}
@@ -0,0 +1,55 @@
+//go:build (linux || darwin) && (amd64 || arm64)
+
+package exec
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"runtime"
+)
+
+// IntermediaryChecksum returns the hex-encoded SHA-256 checksum of the intermediary embedded for the current
+// platform, so that deployments can audit the binary that runs their commands.
+//
+// The intermediary is verified against it whenever it is extracted or reused from the cache, unless a different
+// source has been set with SetIntermediarySource, and a pre-installed intermediary is always verified. An intermediary
+// that is ad-hoc signed after extraction on macOS (see SetAdHocSign) is verified before it is signed, and its signature
+// is verified when it is reused.
+func IntermediaryChecksum() string {
+	return intermediaryChecksums[targetMap[runtime.GOARCH+"-"+runtime.GOOS]]
+}
+
+// verifyIntermediary returns an error if the file at path is not the embedded intermediary for target. It accepts
+// any file if the intermediary is extracted from a different source.
+func verifyIntermediary(path, target string) error {
+	if source != fs.FS(binaries) {
+		return nil
+	}
+	return verifyChecksum(path, target)
+}
+
+// verifyChecksum returns an error if the file at path does not have the checksum of the embedded intermediary for
+// target.
+func verifyChecksum(path, target string) error {
+	expected, ok := intermediaryChecksums[target]
+	if !ok {
+		return fmt.Errorf("%s: no checksum is known for the %s intermediary", path, target)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close() //nolint
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return err
+	}
+	if actual := hex.EncodeToString(h.Sum(nil)); actual != expected {
+		return fmt.Errorf("%s: SHA-256 checksum %s does not match the embedded intermediary's %s", path, actual, expected)
+	}
+	return nil
+}
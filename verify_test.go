@@ -0,0 +1,36 @@
+//go:build (linux || darwin) && (amd64 || arm64)
+
+package exec_test
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/alecthomas/exec"
+)
+
+func TestIntermediaryChecksum(t *testing.T) {
+	f, err := os.Open("intermediary/intermediary-" + exec.Intermediary().Target + ".gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gzr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, gzr); err != nil {
+		t.Fatal(err)
+	}
+	if expected := hex.EncodeToString(h.Sum(nil)); exec.IntermediaryChecksum() != expected {
+		t.Errorf("Expected %s, got %s: run just build to regenerate checksums.go", expected, exec.IntermediaryChecksum())
+	}
+	if err := exec.Command("true").Run(); err != nil {
+		t.Errorf("Expected the embedded intermediary to pass verification: %v", err)
+	}
+}
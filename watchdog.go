@@ -0,0 +1,30 @@
+package exec
+
+import (
+	"fmt"
+)
+
+// CheckWatchdog returns err, wrapped with ErrWatchdogDied if cmd's intermediary was terminated by a signal that this
+// package did not send. It is intended to wrap the result of Run or Wait:
+//
+//	err := exec.CheckWatchdog(cmd, cmd.Run())
+//
+// The intermediary reports a child killed by a signal as an exit status of 128+signal, so an intermediary that itself
+// terminated on a signal was killed externally, eg. by the OOM killer. The command's process group is terminated in
+// that case, but the exit status of the command is lost. Signals sent by context cancellation, ShutdownAll and
+// NotifyAndForward are excluded; signals sent directly with cmd.Process.Signal, or by a replacement cmd.Cancel, are
+// not.
+//
+// A process that has been reparented cannot be adopted again, so a dead intermediary cannot be restarted. Commands run
+// without an intermediary, as with StrategyPdeathsig, are never reported.
+func CheckWatchdog(cmd *Cmd, err error) error {
+	if cmd.ProcessState == nil || cmd.direct || wasSignalled(cmd) {
+		return err
+	}
+	status, ok := cmd.ProcessState.Sys().(interface{ Signaled() bool })
+	if !ok || !status.Signaled() {
+		return err
+	}
+	watchdogFailures.Add(1)
+	return fmt.Errorf("%w: %w", ErrWatchdogDied, err)
+}
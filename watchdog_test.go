@@ -0,0 +1,55 @@
+//go:build (linux || darwin) && (amd64 || arm64)
+
+package exec_test
+
+import (
+	"context"
+	"errors"
+	stdexec "os/exec"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/exec"
+)
+
+func TestCheckWatchdog(t *testing.T) {
+	cmd := exec.Command("sleep", "10")
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Process.Signal(syscall.SIGKILL); err != nil {
+		t.Fatal(err)
+	}
+	if err := exec.CheckWatchdog(cmd, cmd.Wait()); !errors.Is(err, exec.ErrWatchdogDied) {
+		t.Errorf("Expected ErrWatchdogDied, got %v", err)
+	}
+}
+
+func TestCheckWatchdogChildSignalled(t *testing.T) {
+	cmd := exec.Command("sh", "-c", "kill -9 $$")
+	err := exec.CheckWatchdog(cmd, cmd.Run())
+	if err == nil || errors.Is(err, exec.ErrWatchdogDied) {
+		t.Errorf("Expected child failure without ErrWatchdogDied, got %v", err)
+	}
+}
+
+func TestCheckWatchdogCancelled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "sleep", "10")
+	err := exec.CheckWatchdog(cmd, cmd.Run())
+	if err == nil || errors.Is(err, exec.ErrWatchdogDied) {
+		t.Errorf("Expected cancellation without ErrWatchdogDied, got %v", err)
+	}
+}
+
+func TestCheckWatchdogCancelledFromStd(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	cmd := exec.NewFromStd(stdexec.CommandContext(ctx, "sleep", "10"))
+	err := exec.CheckWatchdog(cmd, cmd.Run())
+	if err == nil || errors.Is(err, exec.ErrWatchdogDied) {
+		t.Errorf("Expected cancellation without ErrWatchdogDied, got %v", err)
+	}
+}